@@ -1,120 +1,330 @@
 package cache
 
 import (
+	"context"
+	"hash/maphash"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/cmilhench/x/exp/clock"
 )
 
-type Item struct {
-	Object     interface{}
+type Item[V any] struct {
+	Object     V
 	Expiration int64
+	cost       int64
+}
+
+// Cache maps keys of type K to values of type V, each with an optional TTL.
+//
+// A cache removes expired items in the background until it is closed, its
+// context is done, or it is garbage collected.
+type Cache[K comparable, V any] struct {
+	// the background goroutine only refers to the embedded cache, so that
+	// Cache can be garbage collected and its finalizer stop the goroutine
+	*cache[K, V]
+}
+
+type cache[K comparable, V any] struct {
+	shards []*shard[K, V]
+	seed   maphash.Seed
+	stop   chan struct{}
+	closed sync.Once
+	clock  clock.Clock
+	cost   atomic.Pointer[func(V) int64]
+	codec  Codec
+
+	used       usage
+	maxEntries int64
+	maxCost    int64
+	// next is the shard to evict from next, taking turns between shards
+	next atomic.Uint64
+
+	loadTTL      time.Duration
+	errorTTL     time.Duration
+	refreshAhead time.Duration
+	loadLock     sync.Mutex
+	calls        map[K]*call[V]
+	errs         map[K]failure
+
+	onEvicted atomic.Pointer[func(K, V, Reason)]
+	evictions atomic.Uint64
+}
+
+// Option configures a Cache.
+type Option func(*options)
+
+type options struct {
+	clock      clock.Clock
+	maxEntries int
+	maxCost    int64
+	policy     Policy
+	shards     int
+	codec      Codec
+
+	loadTTL      time.Duration
+	errorTTL     time.Duration
+	refreshAhead time.Duration
 }
 
-type Cache struct {
-	items map[string]Item
-	lock  sync.RWMutex
-	stop  chan struct{}
+// WithClock makes the cache use `c` rather than the real clock for
+// expiration and cleanup, for example a clock.Fake in tests.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
 }
 
-func New(interval time.Duration) *Cache {
-	c := &Cache{
-		items: make(map[string]Item),
-		stop:  make(chan struct{}),
+// WithMaxEntries bounds the cache to `n` items, evicting by the policy set
+// with WithPolicy to make room for more.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
 	}
-	ticker := time.NewTicker(interval)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				clean(c)
-			case <-c.stop:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
-	runtime.SetFinalizer(c, func(c *Cache) {
-		c.stop <- struct{}{}
+}
+
+// WithMaxCost bounds the total cost of the items in the cache to `max`, as
+// measured by the function passed to SetCost, evicting by the policy set with
+// WithPolicy to make room for more. An item costing more than `max` is not
+// cached at all.
+func WithMaxCost(max int64) Option {
+	return func(o *options) {
+		o.maxCost = max
+	}
+}
+
+// WithPolicy sets the eviction policy of a cache bounded by WithMaxEntries
+// or WithMaxCost, LRU by default.
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithShards splits the cache into `n` independently locked shards chosen by
+// the hash of each key, so that operations on different shards do not
+// contend. Any bound set with WithMaxEntries or WithMaxCost holds for the
+// whole cache, which evicts from each shard in turn to make room, so the
+// eviction policy orders the items within each shard rather than overall.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// New returns an untyped cache, removing expired items every `interval`.
+func New(interval time.Duration, opts ...Option) *Cache[string, interface{}] {
+	return NewTypedContext[string, interface{}](context.Background(), interval, opts...)
+}
+
+// NewContext returns an untyped cache, removing expired items every
+// `interval` until `ctx` is done.
+func NewContext(ctx context.Context, interval time.Duration, opts ...Option) *Cache[string, interface{}] {
+	return NewTypedContext[string, interface{}](ctx, interval, opts...)
+}
+
+// NewTyped returns a cache of values of type V by keys of type K, removing
+// expired items every `interval`.
+func NewTyped[K comparable, V any](interval time.Duration, opts ...Option) *Cache[K, V] {
+	return NewTypedContext[K, V](context.Background(), interval, opts...)
+}
+
+// NewTypedContext returns a cache of values of type V by keys of type K,
+// removing expired items every `interval` until `ctx` is done.
+func NewTypedContext[K comparable, V any](ctx context.Context, interval time.Duration, opts ...Option) *Cache[K, V] {
+	o := options{clock: clock.Real(), codec: Gob}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &cache[K, V]{
+		shards: make([]*shard[K, V], max(o.shards, 1)),
+		seed:   maphash.MakeSeed(),
+		stop:   make(chan struct{}),
+		clock:  o.clock,
+		codec:  o.codec,
+
+		maxEntries: int64(o.maxEntries),
+		maxCost:    o.maxCost,
+
+		loadTTL:      o.loadTTL,
+		errorTTL:     o.errorTTL,
+		refreshAhead: o.refreshAhead,
+		calls:        make(map[K]*call[V]),
+		errs:         make(map[K]failure),
+	}
+	n := len(c.shards)
+	for i := range c.shards {
+		c.shards[i] = newShard[K, V](&c.used, ceil(o.maxEntries, n), o.maxCost, o.policy, o.maxEntries > 0 || o.maxCost > 0)
+	}
+	go c.janitor(ctx, c.clock.NewTicker(interval))
+	wrapper := &Cache[K, V]{c}
+	runtime.SetFinalizer(wrapper, func(c *Cache[K, V]) {
+		c.Close()
 	})
-	return c
+	return wrapper
 }
 
-func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+// Close stops removing expired items in the background. The cache remains
+// usable, and still hides expired items from Get.
+func (c *cache[K, V]) Close() error {
+	c.closed.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
+
+func (c *cache[K, V]) Set(key K, value V, ttl time.Duration) {
+	now := c.clock.Now()
 	var exp int64
 	if ttl > 0 {
-		exp = time.Now().Add(ttl).UnixNano()
+		exp = now.Add(ttl).UnixNano()
 	}
-	c.lock.Lock()
-	defer func() {
-		c.lock.Unlock()
-	}()
-	c.items[key] = Item{
+	var cost int64 = 1
+	if fn := c.cost.Load(); fn != nil {
+		cost = (*fn)(value)
+	}
+	s := c.shard(key)
+	evicted := c.reserve(s, key, cost, now.UnixNano())
+	evicted = append(evicted, s.set(key, Item[V]{
 		Object:     value,
 		Expiration: exp,
-	}
+		cost:       cost,
+	}, now.UnixNano())...)
+	// concurrent calls may each have made room for only their own item
+	c.notify(c.shrink(0, 0, now.UnixNano(), evicted))
 }
 
-func (c *Cache) Get(key string) (value interface{}, found bool) {
-	c.lock.RLock()
-	defer func() {
-		c.lock.RUnlock()
-	}()
-	item, found := c.items[key]
+// reserve evicts items until there is room to set the item for `key` costing
+// `cost` in shard `s` within the cache's bounds.
+func (c *cache[K, V]) reserve(s *shard[K, V], key K, cost, now int64) []eviction[K, V] {
+	if c.maxEntries <= 0 && c.maxCost <= 0 {
+		return nil
+	}
+	if c.maxCost > 0 && cost > c.maxCost {
+		// set will not cache it
+		return nil
+	}
+	var entries int64 = 1
+	if old, found := s.peek(key); found {
+		entries, cost = 0, cost-old
+	}
+	return c.shrink(entries, cost, now, nil)
+}
 
-	if !found {
-		// log.Debugf("  - %s not found in cache of %d items %p", key, len(c.items), c)
-		return
+// shrink evicts items from each shard in turn until there is room for
+// `entries` more items costing `cost` within the cache's bounds, appending
+// them to `evicted`.
+func (c *cache[K, V]) shrink(entries, cost, now int64, evicted []eviction[K, V]) []eviction[K, V] {
+	for (c.maxEntries > 0 && c.used.entries.Load()+entries > c.maxEntries) ||
+		(c.maxCost > 0 && c.used.cost.Load()+cost > c.maxCost) {
+		e, ok := c.evictOne(now)
+		if !ok {
+			break
+		}
+		evicted = append(evicted, e)
 	}
-	if item.Expiration > 0 {
-		if time.Now().UnixNano() > item.Expiration {
-			found = false
-			// log.Debugf("  - %s not found in cache of %d items %p", key, len(c.items), c)
-			return
+	return evicted
+}
+
+// evictOne evicts the victim of the next shard that has one.
+func (c *cache[K, V]) evictOne(now int64) (eviction[K, V], bool) {
+	n := uint64(len(c.shards))
+	start := c.next.Add(1)
+	for i := range n {
+		if e, ok := c.shards[(start+i)%n].evictOne(now); ok {
+			return e, true
 		}
 	}
-	value = item.Object
-	// log.Debugf("  - %s found in cache of %d items %p", key, len(c.items), c)
+	return eviction[K, V]{}, false
+}
+
+// SetCost sets the function measuring the cost of each value for the bound
+// set with WithMaxCost, without which every item costs 1. Items already in
+// the cache keep the cost they were added with. Passing nil removes the
+// function.
+func (c *cache[K, V]) SetCost(fn func(V) int64) {
+	if fn == nil {
+		c.cost.Store(nil)
+		return
+	}
+	c.cost.Store(&fn)
+}
+
+func (c *cache[K, V]) Get(key K) (value V, found bool) {
+	value, _, found = c.get(key)
 	return
 }
 
-func (c *Cache) Count() int {
-	c.lock.RLock()
-	defer func() {
-		c.lock.RUnlock()
-	}()
-	return len(c.items)
+func (c *cache[K, V]) get(key K) (value V, expiration int64, found bool) {
+	item, found := c.shard(key).get(key, c.clock.Now().UnixNano())
+	return item.Object, item.Expiration, found
+}
+
+func (c *cache[K, V]) Count() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.count()
+	}
+	return n
 }
 
-func (c *Cache) Delete(key string) {
-	c.lock.Lock()
-	defer func() {
-		c.lock.Unlock()
-	}()
-	delete(c.items, key)
-	// log.Debugf("  - %s removed from cache of %d items %p", key, len(c.items), c)
+func (c *cache[K, V]) Delete(key K) {
+	c.notify(c.shard(key).delete(key, c.clock.Now().UnixNano()))
+	c.forget(key)
 }
 
-func (c *Cache) Flush() {
-	c.lock.Lock()
-	defer func() {
-		c.lock.Unlock()
-	}()
-	c.items = map[string]Item{}
-	// log.Debugf("  - Everything removed from cache of %d items %p", len(c.items), c)
+func (c *cache[K, V]) Flush() {
+	collect := c.onEvicted.Load() != nil
+	now := c.clock.Now().UnixNano()
+	for _, s := range c.shards {
+		c.notify(s.flush(collect, now))
+	}
+	c.loadLock.Lock()
+	c.errs = make(map[K]failure)
+	c.loadLock.Unlock()
+}
+
+func (c *cache[K, V]) shard(key K) *shard[K, V] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
 }
 
 // -- Housekeeping
 
-func clean(c *Cache) {
-	c.lock.Lock()
-	defer func() {
-		c.lock.Unlock()
-	}()
-	now := time.Now().UnixNano()
-	for key, item := range c.items {
-		if item.Expiration > 0 && now > item.Expiration {
-			delete(c.items, key)
+func (c *cache[K, V]) janitor(ctx context.Context, ticker clock.Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			clean(c)
+		case <-c.stop:
+			return
+		case <-ctx.Done():
+			return
 		}
 	}
 }
+
+// clean removes expired items a shard at a time, so that only one shard is
+// locked at once, and then expired failures.
+func clean[K comparable, V any](c *cache[K, V]) {
+	for _, s := range c.shards {
+		c.notify(s.clean(c.clock.Now().UnixNano()))
+	}
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+	now := c.clock.Now().UnixNano()
+	for key, f := range c.errs {
+		if now > f.expiration {
+			delete(c.errs, key)
+		}
+	}
+}
+
+func ceil(n, d int) int {
+	return (n + d - 1) / d
+}
@@ -0,0 +1,336 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/cache"
+	"github.com/cmilhench/x/exp/clock"
+)
+
+func TestCache(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := New(time.Minute, WithClock(clk))
+
+	c.Set("a", 1, time.Second)
+	c.Set("b", "two", 0)
+	if v, found := c.Get("a"); !found || v != 1 {
+		t.Errorf("expected 1, got %v %v", v, found)
+	}
+	clk.Advance(2 * time.Second)
+	if _, found := c.Get("a"); found {
+		t.Errorf("expected a to have expired")
+	}
+	if v, found := c.Get("b"); !found || v != "two" {
+		t.Errorf("expected two, got %v %v", v, found)
+	}
+}
+
+func TestTypedCache(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewTyped[int, string](time.Second, WithClock(clk))
+
+	c.Set(1, "one", time.Second)
+	c.Set(2, "two", time.Hour)
+	if v, found := c.Get(1); !found || v != "one" {
+		t.Errorf("expected one, got %q %v", v, found)
+	}
+	clk.Advance(2 * time.Second)
+	if v, found := c.Get(1); found || v != "" {
+		t.Errorf("expected a miss with the zero value, got %q %v", v, found)
+	}
+	// the cleanup ticker has fired by now, so only the unexpired item remains
+	deadline := time.Now().Add(time.Second)
+	for c.Count() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := c.Count(); n != 1 {
+		t.Errorf("expected 1 item after cleanup, got %d", n)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewTyped[int, int](time.Second, WithClock(clk))
+	for i := range 5000 {
+		c.Set(i, i, time.Second)
+	}
+	for i := range 10 {
+		c.Set(-i-1, i, 0)
+	}
+	clk.Advance(2 * time.Second)
+	// cleanup examines a few hundred items at a time, but goes on while most
+	// of them have expired
+	deadline := time.Now().Add(time.Second)
+	for c.Count() != 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := c.Count(); n != 10 {
+		t.Errorf("expected 10 items after cleanup, got %d", n)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   []int
+	}{
+		// 1 was used least recently
+		{LRU, []int{2, 3, 4}},
+		// 2 was used least often, and less recently than 3
+		{LFU, []int{1, 3, 4}},
+		// 3 leaving the window was not used more often than the main victim 1
+		{TinyLFU, []int{1, 2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			c := NewTyped[int, int](time.Minute, WithMaxEntries(3), WithPolicy(tt.policy))
+			c.Set(1, 1, 0)
+			c.Set(2, 2, 0)
+			c.Set(3, 3, 0)
+			c.Get(1)
+			c.Get(1)
+			c.Get(2)
+			c.Get(3)
+			c.Set(4, 4, 0)
+			if n := c.Count(); n != 3 {
+				t.Errorf("expected 3 items, got %d", n)
+			}
+			for _, key := range tt.want {
+				if _, found := c.Get(key); !found {
+					t.Errorf("expected %d to be cached", key)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	c := NewTyped[string, string](time.Minute, WithMaxCost(10))
+	c.SetCost(func(v string) int64 { return int64(len(v)) })
+	c.Set("a", "aaaa", 0)
+	c.Set("b", "bbbb", 0)
+	c.Set("c", "cccc", 0)
+	if _, found := c.Get("a"); found {
+		t.Errorf("expected a to have been evicted")
+	}
+	c.Set("d", "ddddddddddd", 0)
+	if _, found := c.Get("d"); found {
+		t.Errorf("expected d to be too large to cache")
+	}
+	if n := c.Count(); n != 2 {
+		t.Errorf("expected 2 items, got %d", n)
+	}
+}
+
+func TestMaxCostUntyped(t *testing.T) {
+	c := New(time.Minute, WithMaxCost(10))
+	// without a cost function each item costs 1
+	for i := range 20 {
+		c.Set(fmt.Sprint(i), i, 0)
+	}
+	if n := c.Count(); n != 10 {
+		t.Errorf("expected 10 items, got %d", n)
+	}
+	c.SetCost(func(v any) int64 { return int64(len(v.(string))) })
+	c.Set("a", "aaaaaaaaaa", 0)
+	if n := c.Count(); n != 1 {
+		t.Errorf("expected only the item costing 10, got %d items", n)
+	}
+}
+
+func TestShards(t *testing.T) {
+	c := NewTyped[int, int](time.Minute, WithShards(8), WithMaxEntries(80))
+	for i := range 1000 {
+		c.Set(i, i, 0)
+	}
+	if n := c.Count(); n != 80 {
+		t.Errorf("expected 80 items, got %d", n)
+	}
+	c.Set(1, 1, 0)
+	if v, found := c.Get(1); !found || v != 1 {
+		t.Errorf("expected 1, got %v %v", v, found)
+	}
+	c.Flush()
+	if n := c.Count(); n != 0 {
+		t.Errorf("expected no items, got %d", n)
+	}
+}
+
+func TestShardsMaxCost(t *testing.T) {
+	c := NewTyped[int, string](time.Minute, WithShards(16), WithMaxCost(100))
+	c.SetCost(func(v string) int64 { return int64(len(v)) })
+
+	large := strings.Repeat("x", 50)
+	c.Set(1, large, 0)
+	if v, found := c.Get(1); !found || v != large {
+		t.Errorf("expected an item within the overall bound to be cached")
+	}
+	c.Set(1, large+large, 0)
+	if _, found := c.Get(1); !found {
+		t.Errorf("expected an item replaced at the overall bound to be cached")
+	}
+	c.Set(2, large+large+"x", 0)
+	if _, found := c.Get(2); found {
+		t.Errorf("expected an item above the overall bound not to be cached")
+	}
+	if n := c.Count(); n != 1 {
+		t.Errorf("expected 1 item, got %d", n)
+	}
+}
+
+func TestShardsBounds(t *testing.T) {
+	c := NewTyped[int, string](time.Minute, WithShards(16), WithMaxCost(100))
+	c.SetCost(func(v string) int64 { return int64(len(v)) })
+	for i := range 100 {
+		c.Set(i, strings.Repeat("x", 90), 0)
+	}
+	// more shards than items fit, each of which could hold one alone
+	if n := c.Count(); n != 1 {
+		t.Errorf("expected 1 item costing 90 within a cost of 100, got %d", n)
+	}
+
+	e := NewTyped[int, int](time.Minute, WithShards(64), WithMaxEntries(10))
+	for i := range 1000 {
+		e.Set(i, i, 0)
+	}
+	if n := e.Count(); n != 10 {
+		t.Errorf("expected 10 items, got %d", n)
+	}
+}
+
+// BenchmarkCache measures throughput under a mixed load of 90% reads and 10%
+// writes from every core, compare with -cpu 1,4,16 for example.
+func BenchmarkCache(b *testing.B) {
+	const keys = 1 << 16
+	for _, shards := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := NewTyped[int, int](time.Minute, WithShards(shards))
+			for i := range keys {
+				c.Set(i, i, time.Hour)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+				for pb.Next() {
+					key := r.IntN(keys)
+					if r.IntN(10) == 0 {
+						c.Set(key, key, time.Hour)
+					} else {
+						c.Get(key)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewTyped[string, int](time.Minute, WithClock(clk), WithMaxEntries(2))
+
+	var got []string
+	c.OnEvicted(func(key string, value int, reason Reason) {
+		got = append(got, fmt.Sprintf("%s=%d %v", key, value, reason))
+	})
+	c.Set("a", 1, 0)
+	c.Set("a", 2, 0)
+	c.Set("b", 3, time.Second)
+	c.Set("c", 4, 0)
+	c.Delete("c")
+	clk.Advance(2 * time.Second)
+	c.Set("b", 5, 0)
+	c.Get("b")
+	c.Get("c")
+	c.Flush()
+
+	want := []string{"a=1 Replaced", "a=2 Evicted", "c=4 Deleted", "b=3 Expired", "b=5 Deleted"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if s := c.Stats(); s != (Stats{Hits: 1, Misses: 1, Evictions: 2}) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestOnEvictedExpired(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewTyped[string, int](time.Hour, WithClock(clk))
+
+	var got []string
+	c.OnEvicted(func(key string, value int, reason Reason) {
+		got = append(got, fmt.Sprintf("%s=%d %v", key, value, reason))
+	})
+	c.Set("a", 1, time.Second)
+	c.Set("b", 2, time.Second)
+	c.Set("c", 3, 0)
+	clk.Advance(2 * time.Second)
+	// expired items not yet cleaned up are reported as expired however removed
+	c.Delete("a")
+	c.Flush()
+
+	slices.Sort(got[1:])
+	want := []string{"a=1 Expired", "b=2 Expired", "c=3 Deleted"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if s := c.Stats(); s.Evictions != 2 {
+		t.Errorf("expected 2 evictions, got %d", s.Evictions)
+	}
+}
+
+func TestClose(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	closed := NewContext(ctx, time.Second, WithClock(clk))
+	canceled := NewContext(ctx, time.Second, WithClock(clk))
+	open := New(time.Second, WithClock(clk))
+
+	closed.Set("a", 1, time.Second)
+	canceled.Set("a", 1, time.Second)
+	open.Set("a", 1, time.Second)
+	closed.Close()
+	closed.Close()
+	cancel()
+	// give both time to stop before their tickers fire
+	time.Sleep(10 * time.Millisecond)
+	clk.Advance(2 * time.Second)
+
+	deadline := time.Now().Add(time.Second)
+	for open.Count() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := open.Count(); n != 0 {
+		t.Errorf("expected the open cache to be cleaned, got %d items", n)
+	}
+	if n := closed.Count(); n != 1 {
+		t.Errorf("expected the closed cache not to be cleaned, got %d items", n)
+	}
+	if n := canceled.Count(); n != 1 {
+		t.Errorf("expected the canceled cache not to be cleaned, got %d items", n)
+	}
+	if _, found := closed.Get("a"); found {
+		t.Errorf("expected a closed cache to hide expired items")
+	}
+}
+
+func TestFinalizer(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for range 10 {
+		New(time.Hour)
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("expected unreachable caches to stop, %d goroutines remain", n-before)
+	}
+}
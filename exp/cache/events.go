@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"fmt"
+)
+
+// Reason is why an item was removed from a cache.
+type Reason int
+
+const (
+	// Expired items outlived their TTL.
+	Expired Reason = iota
+	// Deleted items were removed by Delete or Flush.
+	Deleted
+	// Replaced items were overwritten by Set.
+	Replaced
+	// Evicted items were removed to make room in a bounded cache.
+	Evicted
+)
+
+// String returns the string representation of the reason.
+func (r Reason) String() string {
+	var names = []string{
+		"Expired",
+		"Deleted",
+		"Replaced",
+		"Evicted",
+	}
+	if Expired <= r && r <= Evicted {
+		return names[r]
+	}
+	return fmt.Sprintf("%%!Reason(%d)", r)
+}
+
+// Stats counts a cache's lookups and evictions.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	// Evictions counts the items expired or evicted by the cache itself,
+	// rather than deleted or replaced by its user.
+	Evictions uint64
+}
+
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason Reason
+}
+
+// OnEvicted sets a function called with each item removed from the cache and
+// the reason for its removal, for example to release resources it holds. The
+// function is called after the cache is unlocked, so it may use the cache.
+// An item is reported as Expired once its TTL has passed, whether the
+// periodic cleanup or another call removes it. Passing nil removes the
+// function.
+func (c *cache[K, V]) OnEvicted(fn func(K, V, Reason)) {
+	if fn == nil {
+		c.onEvicted.Store(nil)
+		return
+	}
+	c.onEvicted.Store(&fn)
+}
+
+// Stats returns the cache's counters.
+func (c *cache[K, V]) Stats() Stats {
+	stats := Stats{Evictions: c.evictions.Load()}
+	for _, s := range c.shards {
+		stats.Hits += s.hits.Load()
+		stats.Misses += s.misses.Load()
+	}
+	return stats
+}
+
+func (c *cache[K, V]) notify(evicted []eviction[K, V]) {
+	for _, e := range evicted {
+		if e.reason == Expired || e.reason == Evicted {
+			c.evictions.Add(1)
+		}
+	}
+	if fn := c.onEvicted.Load(); fn != nil {
+		for _, e := range evicted {
+			(*fn)(e.key, e.value, e.reason)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"hash/maphash"
+)
+
+// Policy selects which item a bounded cache evicts to make room.
+type Policy int
+
+const (
+	// LRU evicts the least recently used item.
+	LRU Policy = iota
+	// LFU evicts the least frequently used item, the least recently used
+	// among equals.
+	LFU
+	// TinyLFU admits new items to a small LRU window, from which they are
+	// only promoted by evicting an item they have been used more often than,
+	// so that a burst of one-off keys cannot flush the frequently used ones.
+	TinyLFU
+)
+
+// String returns the string representation of the policy.
+func (p Policy) String() string {
+	var names = []string{
+		"LRU",
+		"LFU",
+		"TinyLFU",
+	}
+	if LRU <= p && p <= TinyLFU {
+		return names[p]
+	}
+	return fmt.Sprintf("%%!Policy(%d)", p)
+}
+
+// evictor tracks the keys in a bounded cache to choose which to evict. It is
+// not safe for concurrent use.
+type evictor[K comparable] interface {
+	// push records a newly added key.
+	push(key K)
+	// touch records a use of an existing key.
+	touch(key K)
+	// remove forgets a key.
+	remove(key K)
+	// victim returns the key to evict next, if any.
+	victim() (K, bool)
+}
+
+func newEvictor[K comparable](p Policy, entries int) evictor[K] {
+	switch p {
+	case LFU:
+		return newLFU[K]()
+	case TinyLFU:
+		return newTinyLFU[K](entries)
+	default:
+		return newLRU[K]()
+	}
+}
+
+// -- LRU
+
+type lru[K comparable] struct {
+	order *list.List
+	keys  map[K]*list.Element
+}
+
+func newLRU[K comparable]() *lru[K] {
+	return &lru[K]{order: list.New(), keys: make(map[K]*list.Element)}
+}
+
+func (l *lru[K]) push(key K) {
+	l.keys[key] = l.order.PushFront(key)
+}
+
+func (l *lru[K]) touch(key K) {
+	if e, found := l.keys[key]; found {
+		l.order.MoveToFront(e)
+	}
+}
+
+func (l *lru[K]) remove(key K) {
+	if e, found := l.keys[key]; found {
+		l.order.Remove(e)
+		delete(l.keys, key)
+	}
+}
+
+func (l *lru[K]) victim() (key K, ok bool) {
+	if e := l.order.Back(); e != nil {
+		return e.Value.(K), true
+	}
+	return
+}
+
+func (l *lru[K]) len() int {
+	return l.order.Len()
+}
+
+// -- LFU
+
+type lfuEntry[K comparable] struct {
+	key   K
+	uses  uint64
+	last  uint64
+	index int
+}
+
+// lfuHeap orders entries by uses, then by last use.
+type lfuHeap[K comparable] []*lfuEntry[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].uses != h[j].uses {
+		return h[i].uses < h[j].uses
+	}
+	return h[i].last < h[j].last
+}
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *lfuHeap[K]) Push(x any) {
+	e := x.(*lfuEntry[K])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *lfuHeap[K]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+type lfu[K comparable] struct {
+	heap lfuHeap[K]
+	keys map[K]*lfuEntry[K]
+	tick uint64
+}
+
+func newLFU[K comparable]() *lfu[K] {
+	return &lfu[K]{keys: make(map[K]*lfuEntry[K])}
+}
+
+func (l *lfu[K]) push(key K) {
+	l.tick++
+	e := &lfuEntry[K]{key: key, uses: 1, last: l.tick}
+	l.keys[key] = e
+	heap.Push(&l.heap, e)
+}
+
+func (l *lfu[K]) touch(key K) {
+	if e, found := l.keys[key]; found {
+		l.tick++
+		e.uses++
+		e.last = l.tick
+		heap.Fix(&l.heap, e.index)
+	}
+}
+
+func (l *lfu[K]) remove(key K) {
+	if e, found := l.keys[key]; found {
+		heap.Remove(&l.heap, e.index)
+		delete(l.keys, key)
+	}
+}
+
+func (l *lfu[K]) victim() (key K, ok bool) {
+	if len(l.heap) > 0 {
+		return l.heap[0].key, true
+	}
+	return
+}
+
+// -- W-TinyLFU
+
+// tinyLFU keeps new keys in a window LRU holding about 1% of the keys, and
+// the rest in a main LRU. While the cache has room, keys leave the window for
+// the main LRU freely. Once it is full, a key leaving the window only
+// replaces the main victim if the sketch estimates it has been used more
+// often, and is evicted otherwise.
+type tinyLFU[K comparable] struct {
+	window *lru[K]
+	main   *lru[K]
+	sketch *sketch[K]
+}
+
+func newTinyLFU[K comparable](entries int) *tinyLFU[K] {
+	return &tinyLFU[K]{window: newLRU[K](), main: newLRU[K](), sketch: newSketch[K](entries)}
+}
+
+func (t *tinyLFU[K]) push(key K) {
+	t.sketch.increment(key)
+	t.window.push(key)
+	for t.window.len() > t.limit() {
+		key, _ := t.window.victim()
+		t.window.remove(key)
+		t.main.push(key)
+	}
+}
+
+func (t *tinyLFU[K]) touch(key K) {
+	t.sketch.increment(key)
+	t.window.touch(key)
+	t.main.touch(key)
+}
+
+func (t *tinyLFU[K]) remove(key K) {
+	t.window.remove(key)
+	t.main.remove(key)
+}
+
+// victim is called to make room for a key about to be pushed, which would
+// move the window's victim to the main LRU.
+func (t *tinyLFU[K]) victim() (key K, ok bool) {
+	if t.window.len() < t.limit() {
+		return t.main.victim()
+	}
+	candidate, ok := t.window.victim()
+	if !ok {
+		return t.main.victim()
+	}
+	other, ok := t.main.victim()
+	if !ok || t.sketch.estimate(candidate) <= t.sketch.estimate(other) {
+		return candidate, true
+	}
+	// promote the candidate, evicting the main victim in its place
+	t.window.remove(candidate)
+	t.main.push(candidate)
+	return other, true
+}
+
+func (t *tinyLFU[K]) limit() int {
+	return max((t.window.len()+t.main.len())/100, 1)
+}
+
+// sketch is a count-min sketch of counters saturating at 15, estimating how
+// often each key has been used recently. Counters are halved periodically so
+// that estimates favour recent use.
+type sketch[K comparable] struct {
+	rows    [4][]uint8
+	mask    uint64
+	seed    maphash.Seed
+	added   int
+	samples int
+}
+
+func newSketch[K comparable](entries int) *sketch[K] {
+	width := 64
+	for width < entries {
+		width <<= 1
+	}
+	s := &sketch[K]{mask: uint64(width - 1), seed: maphash.MakeSeed(), samples: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *sketch[K]) indexes(key K) [4]uint64 {
+	h := maphash.Comparable(s.seed, key)
+	h1, h2 := h, h>>32|h<<32|1
+	var idx [4]uint64
+	for i := range idx {
+		idx[i] = (h1 + uint64(i)*h2) & s.mask
+	}
+	return idx
+}
+
+func (s *sketch[K]) increment(key K) {
+	for i, j := range s.indexes(key) {
+		if s.rows[i][j] < 15 {
+			s.rows[i][j]++
+		}
+	}
+	s.added++
+	if s.added >= s.samples {
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.added /= 2
+	}
+}
+
+func (s *sketch[K]) estimate(key K) uint8 {
+	n := uint8(15)
+	for i, j := range s.indexes(key) {
+		n = min(n, s.rows[i][j])
+	}
+	return n
+}
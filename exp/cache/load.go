@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// WithLoadTTL sets the TTL of the values GetOrLoad loads, which otherwise
+// never expire.
+func WithLoadTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.loadTTL = ttl
+	}
+}
+
+// WithErrorTTL makes GetOrLoad remember a loader's error for `ttl`, returning
+// it to callers rather than calling the loader again until it expires.
+func WithErrorTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.errorTTL = ttl
+	}
+}
+
+// WithRefreshAhead makes GetOrLoad reload a value in the background once it
+// is within `d` of expiring, returning the current value in the meantime.
+func WithRefreshAhead(d time.Duration) Option {
+	return func(o *options) {
+		o.refreshAhead = d
+	}
+}
+
+// call is a load in progress, whose result is ready once done is closed.
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type failure struct {
+	err        error
+	expiration int64
+}
+
+// GetOrLoad returns the value for `key`, calling `loader` to load and cache
+// it if it is missing. Concurrent calls for the same key share a single call
+// to `loader`, which is not canceled if the caller's context is.
+func (c *cache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(context.Context, K) (V, error)) (V, error) {
+	if value, exp, found := c.get(key); found {
+		if c.refreshAhead > 0 && exp > 0 && c.clock.Now().Add(c.refreshAhead).UnixNano() > exp {
+			c.load(ctx, key, loader)
+		}
+		return value, nil
+	}
+	var zero V
+	c.loadLock.Lock()
+	f, failed := c.errs[key]
+	if failed && c.clock.Now().UnixNano() > f.expiration {
+		delete(c.errs, key)
+		failed = false
+	}
+	c.loadLock.Unlock()
+	if failed {
+		return zero, f.err
+	}
+	call := c.load(ctx, key, loader)
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// load starts loading the value for `key`, unless it is already loading.
+func (c *cache[K, V]) load(ctx context.Context, key K, loader func(context.Context, K) (V, error)) *call[V] {
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+	if call, found := c.calls[key]; found {
+		return call
+	}
+	call := &call[V]{done: make(chan struct{})}
+	c.calls[key] = call
+	go func() {
+		call.value, call.err = loader(context.WithoutCancel(ctx), key)
+		if call.err == nil {
+			c.Set(key, call.value, c.loadTTL)
+		}
+		c.loadLock.Lock()
+		delete(c.calls, key)
+		if call.err != nil && c.errorTTL > 0 {
+			c.errs[key] = failure{err: call.err, expiration: c.clock.Now().Add(c.errorTTL).UnixNano()}
+		}
+		c.loadLock.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// forget discards any error remembered for `key`.
+func (c *cache[K, V]) forget(key K) {
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+	delete(c.errs, key)
+}
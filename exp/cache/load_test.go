@@ -0,0 +1,106 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/cache"
+	"github.com/cmilhench/x/exp/clock"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := NewTyped[string, int](time.Minute)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		return len(key), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetOrLoad(ctx, "key", loader)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 call to the loader, got %d", n)
+	}
+	for _, v := range results {
+		if v != 3 {
+			t.Errorf("expected 3, got %d", v)
+		}
+	}
+	if v, found := c.Get("key"); !found || v != 3 {
+		t.Errorf("expected the loaded value to be cached, got %v %v", v, found)
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewTyped[string, int](time.Minute, WithClock(clk), WithErrorTTL(time.Second))
+
+	var calls atomic.Int32
+	failing := errors.New("failing")
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		return 0, failing
+	}
+
+	for range 3 {
+		if _, err := c.GetOrLoad(ctx, "key", loader); !errors.Is(err, failing) {
+			t.Errorf("expected %v, got %v", failing, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 call to the loader, got %d", n)
+	}
+	clk.Advance(2 * time.Second)
+	c.GetOrLoad(ctx, "key", loader)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 calls to the loader, got %d", n)
+	}
+}
+
+func TestGetOrLoadRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewTyped[string, int](time.Minute, WithClock(clk), WithLoadTTL(10*time.Second), WithRefreshAhead(2*time.Second))
+
+	var calls atomic.Int32
+	loader := func(ctx context.Context, key string) (int, error) {
+		return int(calls.Add(1)), nil
+	}
+
+	if v, _ := c.GetOrLoad(ctx, "key", loader); v != 1 {
+		t.Errorf("expected 1, got %d", v)
+	}
+	clk.Advance(9 * time.Second)
+	// the current value is returned while the next is loaded
+	if v, _ := c.GetOrLoad(ctx, "key", loader); v != 1 {
+		t.Errorf("expected 1, got %d", v)
+	}
+	deadline := time.Now().Add(time.Second)
+	for v, _ := c.Get("key"); v != 2 && time.Now().Before(deadline); v, _ = c.Get("key") {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(9 * time.Second)
+	if v, _ := c.GetOrLoad(ctx, "key", loader); v != 2 {
+		t.Errorf("expected 2, got %d", v)
+	}
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Codec encodes and decodes cache snapshots.
+type Codec interface {
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+var (
+	// Gob encodes snapshots with encoding/gob. Values held in interfaces,
+	// as in a cache returned by New, must have their types registered with
+	// gob.Register.
+	Gob Codec = gobCodec{}
+	// JSON encodes snapshots with encoding/json. Values held in interfaces
+	// are restored as the types encoding/json decodes into an interface.
+	JSON Codec = jsonCodec{}
+)
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(w io.Writer, v any) error { return gob.NewEncoder(w).Encode(v) }
+func (gobCodec) Decode(r io.Reader, v any) error { return gob.NewDecoder(r).Decode(v) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+func (jsonCodec) Decode(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }
+
+// WithCodec sets the codec used by Save and Load, Gob by default.
+func WithCodec(c Codec) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// snapshot holds the unexpired items in a cache when it was saved.
+type snapshot[K comparable, V any] struct {
+	Saved time.Time
+	Items []record[K, V]
+}
+
+// record is an item in a snapshot, with the TTL it had remaining, or 0 if it
+// does not expire.
+type record[K comparable, V any] struct {
+	Key   K
+	Value V
+	TTL   time.Duration
+}
+
+// Save writes a snapshot of the unexpired items in the cache to `w`.
+func (c *cache[K, V]) Save(w io.Writer) error {
+	now := c.clock.Now()
+	snap := snapshot[K, V]{Saved: now}
+	for _, s := range c.shards {
+		snap.Items = s.snapshot(now.UnixNano(), snap.Items)
+	}
+	return c.codec.Encode(w, snap)
+}
+
+// Load adds the items in a snapshot read from `r` to the cache, each with the
+// TTL it had remaining when the snapshot was saved less the time since, so
+// items that have expired in the meantime are skipped.
+func (c *cache[K, V]) Load(r io.Reader) error {
+	var snap snapshot[K, V]
+	if err := c.codec.Decode(r, &snap); err != nil {
+		return err
+	}
+	elapsed := max(c.clock.Since(snap.Saved), 0)
+	for _, r := range snap.Items {
+		ttl := r.TTL
+		if ttl > 0 {
+			if ttl -= elapsed; ttl <= 0 {
+				continue
+			}
+		}
+		c.Set(r.Key, r.Value, ttl)
+	}
+	return nil
+}
+
+// SaveFile writes a snapshot of the cache to the named file, replacing it
+// atomically.
+func (c *cache[K, V]) SaveFile(name string) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := c.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// LoadFile adds the items in a snapshot read from the named file to the
+// cache.
+func (c *cache[K, V]) LoadFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(f)
+}
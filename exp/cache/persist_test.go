@@ -0,0 +1,91 @@
+package cache_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/cache"
+	"github.com/cmilhench/x/exp/clock"
+)
+
+func TestSaveLoad(t *testing.T) {
+	for _, codec := range []Codec{Gob, JSON} {
+		clk := clock.NewFake(time.Unix(0, 0))
+		c := NewTyped[string, int](time.Minute, WithClock(clk), WithCodec(codec))
+		c.Set("a", 1, 0)
+		c.Set("b", 2, 10*time.Second)
+		c.Set("c", 3, time.Second)
+		clk.Advance(2 * time.Second)
+
+		var buf bytes.Buffer
+		if err := c.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		// restored 5s later
+		clk = clock.NewFake(time.Unix(7, 0))
+		restored := NewTyped[string, int](time.Minute, WithClock(clk), WithCodec(codec))
+		if err := restored.Load(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if n := restored.Count(); n != 2 {
+			t.Errorf("expected 2 items, got %d", n)
+		}
+		if v, found := restored.Get("a"); !found || v != 1 {
+			t.Errorf("expected 1, got %v %v", v, found)
+		}
+		// b had 8s of its TTL remaining when saved
+		clk.Advance(2 * time.Second)
+		if v, found := restored.Get("b"); !found || v != 2 {
+			t.Errorf("expected 2, got %v %v", v, found)
+		}
+		clk.Advance(2 * time.Second)
+		if _, found := restored.Get("b"); found {
+			t.Errorf("expected b to have expired")
+		}
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "cache")
+	c := New(time.Minute, WithCodec(JSON))
+	c.Set("a", "one", time.Hour)
+	if err := c.SaveFile(name); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New(time.Minute, WithCodec(JSON))
+	if err := restored.LoadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if v, found := restored.Get("a"); !found || v != "one" {
+		t.Errorf("expected one, got %v %v", v, found)
+	}
+}
+
+func TestLoadExpired(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewTyped[string, int](time.Minute, WithClock(clk))
+	c.Set("a", 1, 0)
+	c.Set("b", 2, time.Hour)
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// restored after b has expired
+	clk = clock.NewFake(time.Unix(0, 0).Add(2 * time.Hour))
+	restored := NewTyped[string, int](time.Minute, WithClock(clk))
+	if err := restored.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := restored.Count(); n != 1 {
+		t.Errorf("expected 1 item, got %d", n)
+	}
+	if _, found := restored.Get("b"); found {
+		t.Errorf("expected b to have expired since the snapshot")
+	}
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// usage is the number and total cost of the items in a cache, shared by its
+// shards so that the cache's bounds hold across all of them.
+type usage struct {
+	entries atomic.Int64
+	cost    atomic.Int64
+}
+
+// shard is an independently locked part of a cache.
+type shard[K comparable, V any] struct {
+	items map[K]Item[V]
+	lock  sync.RWMutex
+
+	used *usage
+	// total is the cost of the shard's own items
+	total int64
+	// maxCost is the cost of the largest item the cache may hold
+	maxCost int64
+	entries int
+	policy  Policy
+	evict   evictor[K]
+	// evictLock serialises the updates get makes to evict under a read lock.
+	evictLock sync.Mutex
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// newShard returns a shard accounting for its items in `used`, with an
+// evictor sized for about `entries` items if `bounded` is set.
+func newShard[K comparable, V any](used *usage, entries int, maxCost int64, policy Policy, bounded bool) *shard[K, V] {
+	s := &shard[K, V]{
+		items:   make(map[K]Item[V]),
+		used:    used,
+		maxCost: maxCost,
+		entries: entries,
+		policy:  policy,
+	}
+	if bounded {
+		s.evict = s.newEvictor()
+	}
+	return s
+}
+
+// set adds or replaces an item, returning the item it replaced.
+func (s *shard[K, V]) set(key K, item Item[V], now int64) (evicted []eviction[K, V]) {
+	s.lock.Lock()
+	defer func() {
+		s.lock.Unlock()
+	}()
+	old, found := s.items[key]
+	if found {
+		evicted = append(evicted, eviction[K, V]{key: key, value: old.Object, reason: removal(old, now, Replaced)})
+	}
+	if s.maxCost > 0 && item.cost > s.maxCost {
+		s.remove(key)
+		return
+	}
+	s.items[key] = item
+	s.total += item.cost - old.cost
+	s.used.cost.Add(item.cost - old.cost)
+	if s.evict != nil {
+		if found {
+			s.evict.touch(key)
+		} else {
+			s.evict.push(key)
+		}
+	}
+	if !found {
+		s.used.entries.Add(1)
+	}
+	return
+}
+
+// peek returns the cost of the item for `key`, if any, without counting a
+// use.
+func (s *shard[K, V]) peek(key K) (cost int64, found bool) {
+	s.lock.RLock()
+	defer func() {
+		s.lock.RUnlock()
+	}()
+	item, found := s.items[key]
+	return item.cost, found
+}
+
+func (s *shard[K, V]) get(key K, now int64) (item Item[V], found bool) {
+	s.lock.RLock()
+	defer func() {
+		s.lock.RUnlock()
+	}()
+	item, found = s.items[key]
+	if !found {
+		s.misses.Add(1)
+		// log.Debugf("  - %s not found in cache of %d items %p", key, len(s.items), s)
+		return
+	}
+	if item.Expiration > 0 {
+		if now > item.Expiration {
+			// log.Debugf("  - %s not found in cache of %d items %p", key, len(s.items), s)
+			s.misses.Add(1)
+			return Item[V]{}, false
+		}
+	}
+	if s.evict != nil {
+		s.evictLock.Lock()
+		s.evict.touch(key)
+		s.evictLock.Unlock()
+	}
+	s.hits.Add(1)
+	// log.Debugf("  - %s found in cache of %d items %p", key, len(s.items), s)
+	return
+}
+
+func (s *shard[K, V]) count() int {
+	s.lock.RLock()
+	defer func() {
+		s.lock.RUnlock()
+	}()
+	return len(s.items)
+}
+
+func (s *shard[K, V]) delete(key K, now int64) (evicted []eviction[K, V]) {
+	s.lock.Lock()
+	defer func() {
+		s.lock.Unlock()
+	}()
+	if item, found := s.items[key]; found {
+		s.remove(key)
+		evicted = append(evicted, eviction[K, V]{key: key, value: item.Object, reason: removal(item, now, Deleted)})
+	}
+	// log.Debugf("  - %s removed from cache of %d items %p", key, len(s.items), s)
+	return
+}
+
+// flush removes every item, returning them if `collect` is set.
+func (s *shard[K, V]) flush(collect bool, now int64) (evicted []eviction[K, V]) {
+	s.lock.Lock()
+	defer func() {
+		s.lock.Unlock()
+	}()
+	if collect {
+		evicted = make([]eviction[K, V], 0, len(s.items))
+		for key, item := range s.items {
+			evicted = append(evicted, eviction[K, V]{key: key, value: item.Object, reason: removal(item, now, Deleted)})
+		}
+	}
+	s.used.entries.Add(-int64(len(s.items)))
+	s.used.cost.Add(-s.total)
+	s.items = map[K]Item[V]{}
+	s.total = 0
+	if s.evict != nil {
+		s.evict = s.newEvictor()
+	}
+	// log.Debugf("  - Everything removed from cache of %d items %p", len(s.items), s)
+	return
+}
+
+// sweep is the number of items clean examines each time it locks a shard.
+const sweep = 256
+
+// clean removes expired items, examining up to sweep of them each time it
+// locks the shard, and stops once fewer than a quarter of those examined had
+// expired. Each range over a map starts at a random item, so every item is
+// examined eventually, without holding the lock over the whole shard.
+func (s *shard[K, V]) clean(now int64) (evicted []eviction[K, V]) {
+	for {
+		var examined, expired int
+		evicted, examined, expired = s.sample(now, evicted)
+		if examined < sweep || expired*4 < examined {
+			return
+		}
+	}
+}
+
+// sample removes the expired items among up to sweep of the shard's items.
+func (s *shard[K, V]) sample(now int64, evicted []eviction[K, V]) (_ []eviction[K, V], examined, expired int) {
+	s.lock.Lock()
+	defer func() {
+		s.lock.Unlock()
+	}()
+	for key, item := range s.items {
+		if examined == sweep {
+			break
+		}
+		examined++
+		if item.Expiration > 0 && now > item.Expiration {
+			s.remove(key)
+			expired++
+			evicted = append(evicted, eviction[K, V]{key: key, value: item.Object, reason: Expired})
+		}
+	}
+	return evicted, examined, expired
+}
+
+// snapshot appends the shard's unexpired items to `records`.
+func (s *shard[K, V]) snapshot(now int64, records []record[K, V]) []record[K, V] {
+	s.lock.RLock()
+	defer func() {
+		s.lock.RUnlock()
+	}()
+	for key, item := range s.items {
+		var ttl time.Duration
+		if item.Expiration > 0 {
+			// an item expiring now would be restored without a TTL
+			if now >= item.Expiration {
+				continue
+			}
+			ttl = time.Duration(item.Expiration - now)
+		}
+		records = append(records, record[K, V]{Key: key, Value: item.Object, TTL: ttl})
+	}
+	return records
+}
+
+func (s *shard[K, V]) newEvictor() evictor[K] {
+	entries := s.entries
+	if entries == 0 {
+		entries = 1024
+	}
+	return newEvictor[K](s.policy, entries)
+}
+
+// remove deletes an item, s.lock must be held for writing.
+func (s *shard[K, V]) remove(key K) {
+	item, found := s.items[key]
+	if !found {
+		return
+	}
+	delete(s.items, key)
+	s.total -= item.cost
+	s.used.entries.Add(-1)
+	s.used.cost.Add(-item.cost)
+	if s.evict != nil {
+		s.evict.remove(key)
+	}
+}
+
+// evictOne evicts the shard's victim, if it has one.
+func (s *shard[K, V]) evictOne(now int64) (evicted eviction[K, V], ok bool) {
+	s.lock.Lock()
+	defer func() {
+		s.lock.Unlock()
+	}()
+	key, ok := s.evict.victim()
+	if !ok {
+		return
+	}
+	evicted = eviction[K, V]{key: key, value: s.items[key].Object, reason: removal(s.items[key], now, Evicted)}
+	s.remove(key)
+	return
+}
+
+// removal returns the reason for removing `item` at `now`: Expired if it has
+// expired, whether or not it has been cleaned up yet, and `reason` otherwise.
+func removal[V any](item Item[V], now int64, reason Reason) Reason {
+	if item.Expiration > 0 && now > item.Expiration {
+		return Expired
+	}
+	return reason
+}
@@ -0,0 +1,53 @@
+// Package clock abstracts time so that code depending on it can be tested
+// deterministically with a manually advanced fake.
+package clock
+
+import (
+	"time"
+)
+
+// Clock provides the subset of the time package used for scheduling.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the interface of a time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the interface of a time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real returns a Clock backed by the time package.
+func Real() Clock {
+	return system{}
+}
+
+type system struct{}
+
+func (system) Now() time.Time                         { return time.Now() }
+func (system) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (system) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (system) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (system) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (system) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
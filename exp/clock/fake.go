@@ -0,0 +1,167 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when Advance is called, firing any
+// timers and tickers that fall due on the way.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters map[*fakeTimer]struct{}
+}
+
+// NewFake returns a Fake clock set to `now`.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now, waiters: make(map[*fakeTimer]struct{})}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After waits for the fake duration to elapse and then sends the fake time.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep blocks until the fake duration has elapsed.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// NewTimer returns a timer that fires once the fake duration has elapsed.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker returns a ticker that fires every time the fake duration elapses.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// Advance moves the fake time forward by `d`, firing in order each timer and
+// ticker that falls due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := f.now.Add(d)
+	for {
+		var next *fakeTimer
+		for t := range f.waiters {
+			if !t.when.After(end) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		f.now = next.when
+		select {
+		case next.c <- f.now:
+		default: // like time.Ticker, drop ticks for slow receivers
+		}
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			delete(f.waiters, next)
+		}
+	}
+	f.now = end
+	f.changed.Broadcast()
+}
+
+// BlockUntil waits until at least `n` timers and tickers are waiting to fire,
+// so a test can be sure the code under test has scheduled them before
+// calling Advance.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+type fakeTimer struct {
+	f      *Fake
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop prevents the timer from firing, reporting whether it was waiting to.
+// As with time.Timer since Go 1.23, a stale value is discarded.
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	_, active := t.f.waiters[t]
+	delete(t.f.waiters, t)
+	t.drain()
+	t.f.changed.Broadcast()
+	return active
+}
+
+// Reset schedules the timer to fire after `d`, reporting whether it was
+// already waiting to.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	_, active := t.f.waiters[t]
+	t.drain()
+	if t.period > 0 {
+		t.period = d
+	}
+	t.when = t.f.now.Add(d)
+	if d <= 0 && t.period == 0 {
+		delete(t.f.waiters, t)
+		t.c <- t.when
+		return active
+	}
+	t.f.waiters[t] = struct{}{}
+	t.f.changed.Broadcast()
+	return active
+}
+
+func (t *fakeTimer) drain() {
+	select {
+	case <-t.c:
+	default:
+	}
+}
+
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	t.fakeTimer.Reset(d)
+}
@@ -0,0 +1,58 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/clock"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Unix(0, 0)
+	clk := NewFake(start)
+
+	timer := clk.NewTimer(150 * time.Millisecond)
+	ticker := clk.NewTicker(100 * time.Millisecond)
+	stopped := clk.NewTimer(50 * time.Millisecond)
+	stopped.Stop()
+
+	clk.Advance(120 * time.Millisecond)
+	if got := <-ticker.C(); !got.Equal(start.Add(100 * time.Millisecond)) {
+		t.Errorf("expected a tick at 100ms, got %v", got.Sub(start))
+	}
+	select {
+	case <-timer.C():
+		t.Errorf("timer fired early")
+	default:
+	}
+
+	clk.Advance(100 * time.Millisecond)
+	if got := <-timer.C(); !got.Equal(start.Add(150 * time.Millisecond)) {
+		t.Errorf("expected the timer at 150ms, got %v", got.Sub(start))
+	}
+	if got := <-ticker.C(); !got.Equal(start.Add(200 * time.Millisecond)) {
+		t.Errorf("expected a tick at 200ms, got %v", got.Sub(start))
+	}
+	select {
+	case <-stopped.C():
+		t.Errorf("stopped timer fired")
+	default:
+	}
+	if got := clk.Since(start); got != 220*time.Millisecond {
+		t.Errorf("expected 220ms to have passed, got %v", got)
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		clk.Sleep(time.Second)
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	<-done
+}
@@ -2,28 +2,53 @@ package identifiers
 
 import (
 	"time"
+
+	"github.com/cmilhench/x/exp/clock"
 )
 
+// Option configures a Creator.
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock makes a Creator use `c` rather than the real clock, for example a
+// clock.Fake in tests. Creator waits a millisecond on `c` before returning,
+// as it does whenever more than 4095 identifiers are made within a
+// millisecond, so a fake clock must be advanced for it to return.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
 // Creator generated a new unique identifier with the following characteristics
 // 41 bits = milliseconds from epoch (max:2199023255551 = ~69 years)
 // 10 bits = shard (max:1024)
 // 12 bits = auto-incrementing and wrapping index (max:4095) see %
 
-func Creator(shard uint16) func() uint64 {
+func Creator(shard uint16, opts ...Option) func() uint64 {
+	o := options{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	e := int64(1577836800000) // time.Parse(time.RFC3339, "2020-01-01T00:00:00Z")
-	l := time.Now().UnixMilli() - e
+	l := o.clock.Now().UnixMilli() - e
 	i := shard % 1024
 	s := 0
-	time.Sleep(time.Millisecond)
+	// don't reuse the millisecond of a previous Creator for this shard
+	o.clock.Sleep(time.Millisecond)
 	return func() uint64 {
 		var hash uint64
-		n := time.Now().UnixMilli() - e
+		n := o.clock.Now().UnixMilli() - e
 		if n == l {
 			s = (s + 1) % 4095 // don't overflow the last 12 bits
 			if s == 0 {
 				// we have overflowed so wait until the next millisecond
 				for n <= l {
-					n = time.Now().UnixMilli() - e
+					o.clock.Sleep(time.UnixMilli(l + e + 1).Sub(o.clock.Now()))
+					n = o.clock.Now().UnixMilli() - e
 				}
 			}
 		} else {
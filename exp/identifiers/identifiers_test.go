@@ -3,7 +3,9 @@ package identifiers_test
 import (
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/cmilhench/x/exp/clock"
 	. "github.com/cmilhench/x/exp/identifiers"
 )
 
@@ -42,3 +44,42 @@ func Test_Creator(t *testing.T) {
 		}
 	}
 }
+
+func Test_CreatorWithClock(t *testing.T) {
+	clk := clock.NewFake(time.UnixMilli(1577836800000).Add(time.Hour))
+	created := make(chan func() uint64)
+	go func() {
+		created <- Creator(7, WithClock(clk))
+	}()
+	// the Creator waits for the next millisecond before returning
+	clk.BlockUntil(1)
+	clk.Advance(time.Millisecond)
+	gen := <-created
+
+	// identifiers within a millisecond share it, with increasing indexes
+	for want := uint64(0); want <= 2; want++ {
+		at, shard, index, _ := Parse(gen())
+		if !at.Equal(clk.Now()) || shard != 7 || index != want {
+			t.Errorf("expected %v 7 %d, got %v %d %d", clk.Now(), want, at, shard, index)
+		}
+	}
+	clk.Advance(time.Millisecond)
+	if _, _, index, _ := Parse(gen()); index != 0 {
+		t.Errorf("expected the index to restart in a new millisecond, got %d", index)
+	}
+
+	// once the indexes run out the Creator waits for the next millisecond
+	for range 4094 {
+		gen()
+	}
+	done := make(chan uint64)
+	go func() {
+		done <- gen()
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Millisecond)
+	at, _, index, _ := Parse(<-done)
+	if !at.Equal(clk.Now()) || index != 0 {
+		t.Errorf("expected %v 0, got %v %d", clk.Now(), at, index)
+	}
+}
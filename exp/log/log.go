@@ -42,6 +42,6 @@ func Debugf(format string, args ...interface{}) {
 // Arguments are handled in the manner of fmt.Printf.
 func Panicf(format string, args ...any) {
 	s := fmt.Sprintf(format, args...)
-	stdErr.Printf(s)
+	stdErr.Print(s)
 	panic(s)
 }
@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Scaling bounds the number of workers used by AutoScale. A worker is retired
+// once it has been idle for `Idle`, or never if `Idle` is not positive.
+// `OnResize`, if set, is called with the number of workers each time it
+// changes, one call at a time and in order, while the pool is locked.
+type Scaling struct {
+	Min      int
+	Max      int
+	Idle     time.Duration
+	OnResize func(size int)
+}
+
+// AutoScale processes values from the input channel using the provided
+// function `fn` on a pool of between `s.Min` and `s.Max` workers, sending the
+// results to a single output channel in no particular order.
+// A worker is added whenever a value arrives and none is free to take it, and
+// idle workers are retired down to the minimum.
+// The function stops when the input channel is closed or the context is canceled.
+func AutoScale[T, O any](ctx context.Context, in <-chan T, s Scaling, fn func(context.Context, T) O) <-chan O {
+	s.Min = max(s.Min, 1)
+	s.Max = max(s.Max, s.Min)
+	out := make(chan O)
+	jobs := make(chan T)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	size := 0
+
+	worker := func() {
+		defer wg.Done()
+		// a nil channel never retires the worker
+		var timeout <-chan time.Time
+		reset := func() {}
+		if s.Idle > 0 {
+			idle := time.NewTimer(s.Idle)
+			defer idle.Stop()
+			timeout = idle.C
+			reset = func() { idle.Reset(s.Idle) }
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-jobs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- fn(ctx, v):
+				case <-ctx.Done():
+					return
+				}
+				reset()
+			case <-timeout:
+				mu.Lock()
+				if size > s.Min {
+					size--
+					if s.OnResize != nil {
+						s.OnResize(size)
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Unlock()
+				reset()
+			}
+		}
+	}
+	// grow adds a worker if there is room, reporting whether it did.
+	grow := func() bool {
+		mu.Lock()
+		if size >= s.Max {
+			mu.Unlock()
+			return false
+		}
+		size++
+		wg.Add(1)
+		go worker()
+		if s.OnResize != nil {
+			s.OnResize(size)
+		}
+		mu.Unlock()
+		return true
+	}
+	for i := 0; i < s.Min; i++ {
+		grow()
+	}
+
+	go func() {
+		defer close(out)
+		defer wg.Wait()
+		defer close(jobs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- v:
+					continue
+				default:
+				}
+				// every worker is busy
+				grow()
+				select {
+				case jobs <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
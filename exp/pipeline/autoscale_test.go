@@ -0,0 +1,80 @@
+package pipeline_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestAutoScale(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// a burst of values followed by a trickle
+	input := make(chan int)
+	go func() {
+		defer close(input)
+		for i := 1; i <= 8; i++ {
+			input <- i
+		}
+		time.Sleep(200 * time.Millisecond)
+		input <- 9
+		input <- 10
+	}()
+
+	var mu sync.Mutex
+	var sizes []int
+	output := AutoScale(ctx, input, Scaling{Min: 1, Max: 4, Idle: 50 * time.Millisecond, OnResize: func(size int) {
+		mu.Lock()
+		sizes = append(sizes, size)
+		mu.Unlock()
+	}}, func(ctx context.Context, val int) int {
+		time.Sleep(20 * time.Millisecond)
+		return val * 2
+	})
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+	slices.Sort(result)
+
+	AssertResults(t, result, []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20})
+	mu.Lock()
+	defer mu.Unlock()
+	if slices.Max(sizes) != 4 {
+		t.Errorf("expected the pool to grow to 4 workers, sizes were %v", sizes)
+	}
+	if i := slices.Index(sizes, 4); !slices.Contains(sizes[i:], 1) {
+		t.Errorf("expected the pool to shrink back to 1 worker, sizes were %v", sizes)
+	}
+}
+
+func TestAutoScaleNoIdle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3)
+
+	var sizes []int
+	// workers are never retired, nor spin waiting to be
+	output := AutoScale(ctx, input, Scaling{Min: 1, Max: 2, OnResize: func(size int) {
+		sizes = append(sizes, size)
+	}}, func(ctx context.Context, val int) int {
+		time.Sleep(20 * time.Millisecond)
+		return val
+	})
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+	slices.Sort(result)
+
+	AssertResults(t, result, []int{1, 2, 3})
+	if slices.Contains(sizes[1:], 1) {
+		t.Errorf("expected no worker to be retired, sizes were %v", sizes)
+	}
+}
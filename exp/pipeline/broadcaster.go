@@ -0,0 +1,178 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// SubscriberPolicy determines what a Broadcaster does when a subscriber's
+// buffer is full.
+type SubscriberPolicy int
+
+const (
+	// WaitForSubscriber holds back every subscriber until there is room.
+	WaitForSubscriber SubscriberPolicy = iota
+	// SkipSubscriber drops the value for that subscriber only.
+	SkipSubscriber
+	// DisconnectSubscriber unsubscribes the subscriber, closing its channel.
+	DisconnectSubscriber
+)
+
+// String returns the string representation of the policy.
+func (p SubscriberPolicy) String() string {
+	var names = []string{
+		"WaitForSubscriber",
+		"SkipSubscriber",
+		"DisconnectSubscriber",
+	}
+	if WaitForSubscriber <= p && p <= DisconnectSubscriber {
+		return names[p]
+	}
+	return fmt.Sprintf("%%!SubscriberPolicy(%d)", p)
+}
+
+// Broadcaster sends each value from an input channel to every current
+// subscriber. Unlike Broadcast, subscribers can join and leave at any time
+// and each has its own buffer and policy for when it falls behind.
+type Broadcaster[T any] struct {
+	mu     sync.Mutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// Subscription is a subscriber's view of a Broadcaster.
+type Subscription[T any] struct {
+	b       *Broadcaster[T]
+	ch      chan T
+	policy  SubscriberPolicy
+	dropped atomic.Uint64
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewBroadcaster starts broadcasting values from the input channel. All
+// subscriptions are closed when the input channel is closed or the context is
+// canceled.
+func NewBroadcaster[T any](ctx context.Context, in <-chan T) *Broadcaster[T] {
+	b := &Broadcaster[T]{subs: make(map[*Subscription[T]]struct{})}
+	go func() {
+		defer b.close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				b.mu.Lock()
+				subs := make([]*Subscription[T], 0, len(b.subs))
+				for s := range b.subs {
+					subs = append(subs, s)
+				}
+				b.mu.Unlock()
+				for _, s := range subs {
+					s.send(ctx, v)
+				}
+			}
+		}
+	}()
+	return b
+}
+
+// Subscribe adds a subscriber receiving values sent from now on, buffering up
+// to `buffer` of them before `policy` applies.
+func (b *Broadcaster[T]) Subscribe(buffer int, policy SubscriberPolicy) *Subscription[T] {
+	s := &Subscription[T]{b: b, ch: make(chan T, buffer), policy: policy, done: make(chan struct{})}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.close()
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Count returns the number of current subscribers.
+func (b *Broadcaster[T]) Count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *Broadcaster[T]) remove(s *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, s)
+}
+
+func (b *Broadcaster[T]) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		s.close()
+	}
+	clear(b.subs)
+}
+
+// Out returns the channel the subscriber receives values on.
+func (s *Subscription[T]) Out() <-chan T {
+	return s.ch
+}
+
+// Dropped returns the number of values skipped because the subscriber was behind.
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe stops the subscriber receiving values and closes its channel.
+func (s *Subscription[T]) Unsubscribe() {
+	s.close()
+	s.b.remove(s)
+}
+
+// close closes the subscriber's channel, interrupting any send waiting on it.
+func (s *Subscription[T]) close() {
+	s.once.Do(func() { close(s.done) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+func (s *Subscription[T]) send(ctx context.Context, v T) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	select {
+	case s.ch <- v:
+		s.mu.Unlock()
+		return
+	default:
+	}
+	switch s.policy {
+	case WaitForSubscriber:
+		select {
+		case s.ch <- v:
+		case <-s.done:
+		case <-ctx.Done():
+		}
+		s.mu.Unlock()
+	case SkipSubscriber:
+		s.dropped.Add(1)
+		s.mu.Unlock()
+	case DisconnectSubscriber:
+		s.mu.Unlock()
+		s.Unsubscribe()
+	}
+}
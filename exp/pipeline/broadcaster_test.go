@@ -0,0 +1,83 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestBroadcaster(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := make(chan int)
+	b := NewBroadcaster(ctx, input)
+
+	fast := b.Subscribe(0, WaitForSubscriber)
+	skip := b.Subscribe(1, SkipSubscriber)
+	gone := b.Subscribe(1, DisconnectSubscriber)
+
+	var result []int
+	input <- 1
+	result = append(result, <-fast.Out())
+	input <- 2
+	result = append(result, <-fast.Out())
+	// a late subscriber only sees values sent after it joins
+	late := b.Subscribe(10, WaitForSubscriber)
+	input <- 3
+	close(input)
+	// fast is closed once every value has been offered to everyone
+	for val := range fast.Out() {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2, 3})
+	result = nil
+	for val := range skip.Out() {
+		result = append(result, val)
+	}
+	AssertResults(t, result, []int{1})
+	if n := skip.Dropped(); n != 2 {
+		t.Errorf("expected 2 values dropped, got %d", n)
+	}
+	result = nil
+	for val := range gone.Out() {
+		result = append(result, val)
+	}
+	AssertResults(t, result, []int{1})
+	result = nil
+	for val := range late.Out() {
+		result = append(result, val)
+	}
+	AssertResults(t, result, []int{3})
+}
+
+func TestBroadcasterUnsubscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := make(chan int)
+	b := NewBroadcaster(ctx, input)
+
+	// a blocked subscriber leaving releases the others
+	stuck := b.Subscribe(0, WaitForSubscriber)
+	other := b.Subscribe(10, WaitForSubscriber)
+	input <- 1
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		stuck.Unsubscribe()
+	}()
+	input <- 2
+	close(input)
+
+	var result []int
+	for val := range other.Out() {
+		result = append(result, val)
+	}
+	AssertResults(t, result, []int{1, 2})
+	if n := b.Count(); n != 0 {
+		t.Errorf("expected no subscribers, got %d", n)
+	}
+}
@@ -0,0 +1,200 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a type-erased pipeline stage as used by Pipeline.Then.
+// Typed stages are adapted with Source and Step.
+type Stage func(context.Context, <-chan any) <-chan any
+
+// Source adapts a function producing a channel, such as Generator, into a
+// Stage that ignores its input. It is normally the first stage of a Pipeline.
+func Source[T any](fn func(context.Context) <-chan T) Stage {
+	return func(ctx context.Context, _ <-chan any) <-chan any {
+		return erase(ctx, fn(ctx))
+	}
+}
+
+// Step adapts a typed stage, such as a closure over Filter, Map or Chunk,
+// into a Stage. Values of the wrong type panic, as they indicate stages that
+// have been wired together incorrectly.
+func Step[I, O any](fn func(context.Context, <-chan I) <-chan O) Stage {
+	return func(ctx context.Context, in <-chan any) <-chan any {
+		return erase(ctx, fn(ctx, typed[I](ctx, in)))
+	}
+}
+
+func typed[T any](ctx context.Context, in <-chan any) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range in {
+			// a nil interface value has no dynamic type to assert
+			var t T
+			if v != nil {
+				t = v.(T)
+			}
+			select {
+			case out <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func erase[T any](ctx context.Context, in <-chan T) <-chan any {
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		for v := range in {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// StageMetrics is a snapshot of the counters for a single named stage.
+type StageMetrics struct {
+	Name string
+	// In is the number of values the stage has received.
+	In uint64
+	// Out is the number of values the stage has sent.
+	Out uint64
+	// Dropped is the number of values lost to cancellation on their way into
+	// or out of the stage.
+	Dropped uint64
+	// InFlight is In minus Out. For stages that send one value for each value
+	// received, such as Map, it is the number of values being processed.
+	InFlight int64
+	// BlockedRecv is the time spent waiting for the previous stage to send.
+	BlockedRecv time.Duration
+	// BlockedSend is the time spent waiting for the next stage to receive.
+	BlockedSend time.Duration
+}
+
+type stage struct {
+	name        string
+	in, out     atomic.Uint64
+	dropped     atomic.Uint64
+	blockedRecv atomic.Int64
+	blockedSend atomic.Int64
+}
+
+// Pipeline composes named stages, threading the context and channels between
+// them and counting the values that pass through each one.
+type Pipeline struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	stages []*stage
+	out    <-chan any
+}
+
+// New returns an empty pipeline bound to the given context.
+func New(ctx context.Context) *Pipeline {
+	return &Pipeline{ctx: ctx}
+}
+
+// Then appends a named stage, wiring its input to the output of the previous
+// stage, and returns the pipeline to allow chaining.
+func (p *Pipeline) Then(name string, fn Stage) *Pipeline {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := &stage{name: name}
+	p.stages = append(p.stages, s)
+	var in <-chan any
+	if p.out != nil {
+		in = s.receive(p.ctx, p.out)
+	}
+	p.out = s.send(p.ctx, fn(p.ctx, in))
+	return p
+}
+
+// Out returns the output channel of the last stage.
+func (p *Pipeline) Out() <-chan any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.out
+}
+
+// Output returns the output channel of the last stage of `p` as a typed channel.
+func Output[T any](p *Pipeline) <-chan T {
+	return typed[T](p.ctx, p.Out())
+}
+
+// Metrics returns a snapshot of the counters for each stage, in order.
+func (p *Pipeline) Metrics() []StageMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make([]StageMetrics, len(p.stages))
+	for i, s := range p.stages {
+		in, out := s.in.Load(), s.out.Load()
+		result[i] = StageMetrics{
+			Name:        s.name,
+			In:          in,
+			Out:         out,
+			Dropped:     s.dropped.Load(),
+			InFlight:    int64(in) - int64(out),
+			BlockedRecv: time.Duration(s.blockedRecv.Load()),
+			BlockedSend: time.Duration(s.blockedSend.Load()),
+		}
+	}
+	return result
+}
+
+// receive relays values from the previous stage, timing how long it waits.
+func (s *stage) receive(ctx context.Context, in <-chan any) <-chan any {
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		for {
+			start := time.Now()
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				s.blockedRecv.Add(int64(time.Since(start)))
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+					s.in.Add(1)
+				case <-ctx.Done():
+					s.dropped.Add(1)
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// send relays values to the next stage, timing how long it waits.
+func (s *stage) send(ctx context.Context, in <-chan any) <-chan any {
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		for v := range in {
+			start := time.Now()
+			select {
+			case out <- v:
+				s.blockedSend.Add(int64(time.Since(start)))
+				s.out.Add(1)
+			case <-ctx.Done():
+				s.dropped.Add(1)
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,90 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestPipeline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	p := New(ctx).
+		Then("generate", Source(func(ctx context.Context) <-chan int {
+			return Generator(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+		})).
+		Then("filter", Step(func(ctx context.Context, in <-chan int) <-chan int {
+			return Filter(ctx, in, func(val int) bool { return val%2 == 0 })
+		})).
+		Then("map", Step(func(ctx context.Context, in <-chan int) <-chan int {
+			return Map(ctx, 0, in, func(ctx context.Context, id int, val int) int {
+				time.Sleep(10 * time.Millisecond)
+				return val * 2
+			})
+		})).
+		Then("chunk", Step(func(ctx context.Context, in <-chan int) <-chan []int {
+			return Chunk(ctx, in, 2)
+		})).
+		Then("reduce", Step(func(ctx context.Context, in <-chan []int) <-chan int {
+			return Reduce(ctx, in, func(acc int, val []int) int {
+				return acc + len(val)
+			}, 0, -1)
+		}))
+
+	var result []int
+	for val := range Output[int](p) {
+		result = append(result, val)
+	}
+	AssertResults(t, result, []int{5})
+
+	metrics := p.Metrics()
+	expected := []struct {
+		name    string
+		in, out uint64
+	}{
+		{"generate", 0, 10},
+		{"filter", 10, 5},
+		{"map", 5, 5},
+		{"chunk", 5, 3},
+		{"reduce", 3, 1},
+	}
+	if len(metrics) != len(expected) {
+		t.Fatalf("expected %d stages, got %d", len(expected), len(metrics))
+	}
+	for i, e := range expected {
+		m := metrics[i]
+		if m.Name != e.name || m.In != e.in || m.Out != e.out {
+			t.Errorf("stage %d: expected %s in=%d out=%d, got %s in=%d out=%d", i, e.name, e.in, e.out, m.Name, m.In, m.Out)
+		}
+	}
+	// the slow map stage starves the stages after it
+	if metrics[3].BlockedRecv < 40*time.Millisecond {
+		t.Errorf("expected chunk to wait on map, waited %v", metrics[3].BlockedRecv)
+	}
+}
+
+func TestPipelineNil(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	errFailed := errors.New("failed")
+	p := New(ctx).
+		Then("generate", Source(func(ctx context.Context) <-chan error {
+			return Generator(ctx, nil, errFailed, nil)
+		})).
+		Then("filter", Step(func(ctx context.Context, in <-chan error) <-chan error {
+			return Filter(ctx, in, func(err error) bool { return true })
+		}))
+
+	var result []error
+	for err := range Output[error](p) {
+		result = append(result, err)
+	}
+	if len(result) != 3 || result[0] != nil || result[1] != errFailed || result[2] != nil {
+		t.Errorf("expected [<nil> failed <nil>], got %v", result)
+	}
+}
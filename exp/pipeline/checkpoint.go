@@ -0,0 +1,187 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Store persists, by name, the offset a source should resume from.
+type Store interface {
+	Load(name string) (uint64, error)
+	Save(name string, offset uint64) error
+}
+
+// MemoryStore is a Store held in memory, useful for tests and for resuming
+// within the lifetime of a process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	offsets map[string]uint64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{offsets: make(map[string]uint64)}
+}
+
+// Load returns the saved offset, or zero if there is none.
+func (s *MemoryStore) Load(name string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offsets[name], nil
+}
+
+// Save records the offset.
+func (s *MemoryStore) Save(name string, offset uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offsets[name] = offset
+	return nil
+}
+
+// FileStore is a Store that keeps each offset in a file named after the
+// source in a directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore writing to `dir`, which must exist.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Load returns the saved offset, or zero if there is none.
+func (s *FileStore) Load(name string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// Save records the offset, replacing the file atomically so a crash cannot
+// leave it half written.
+func (s *FileStore) Save(name string, offset uint64) error {
+	f, err := os.CreateTemp(s.dir, name+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(strconv.FormatUint(offset, 10)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(s.dir, name))
+}
+
+// Offset is a value from a checkpointed source along with its position.
+type Offset[T any] struct {
+	Offset uint64
+	Value  T
+}
+
+// Checkpoint tracks which values of a named source have been acknowledged by
+// the sink and saves the position to resume from, giving at-least-once
+// delivery: values sent but not acknowledged before a restart are sent again.
+type Checkpoint struct {
+	store Store
+	name  string
+	mu    sync.Mutex
+	next  uint64
+	acked map[uint64]struct{}
+}
+
+// NewCheckpoint returns a Checkpoint for the named source, loading the
+// position to resume from from the store.
+func NewCheckpoint(store Store, name string) (*Checkpoint, error) {
+	next, err := store.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{store: store, name: name, next: next, acked: make(map[uint64]struct{})}, nil
+}
+
+// Offset returns the position to resume from, that is the offset of the first
+// value not yet acknowledged.
+func (c *Checkpoint) Offset() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.next
+}
+
+// Ack acknowledges that the value at `offset` has been fully processed.
+// Acknowledgements may arrive out of order; the saved position only advances
+// past offsets that have all been acknowledged.
+func (c *Checkpoint) Ack(offset uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset < c.next {
+		return nil
+	}
+	c.acked[offset] = struct{}{}
+	next := c.next
+	for {
+		if _, found := c.acked[next]; !found {
+			break
+		}
+		delete(c.acked, next)
+		next++
+	}
+	if next == c.next {
+		return nil
+	}
+	c.next = next
+	return c.store.Save(c.name, next)
+}
+
+// Resume numbers the values of a source from the checkpoint's position. The
+// source function is given that position so it can skip the values already
+// acknowledged, for example by seeking in a file or querying from an id.
+func Resume[T any](ctx context.Context, c *Checkpoint, src func(ctx context.Context, offset uint64) <-chan T) <-chan Offset[T] {
+	out := make(chan Offset[T])
+	go func() {
+		defer close(out)
+		offset := c.Offset()
+		in := src(ctx, offset)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- Offset[T]{Offset: offset, Value: v}:
+					offset++
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// GeneratorFrom generates values from a slice like Generator, starting from
+// the checkpoint's position.
+func GeneratorFrom[T any](ctx context.Context, c *Checkpoint, in ...T) <-chan Offset[T] {
+	return Resume(ctx, c, func(ctx context.Context, offset uint64) <-chan T {
+		return Generator(ctx, in[min(offset, uint64(len(in))):]...)
+	})
+}
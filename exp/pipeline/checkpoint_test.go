@@ -0,0 +1,63 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestCheckpoint(t *testing.T) {
+	stores := []struct {
+		name  string
+		store Store
+	}{
+		{"memory", NewMemoryStore()},
+		{"file", NewFileStore(t.TempDir())},
+	}
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+			// the first run acknowledges 1-3 and 5 before stopping
+			cp, err := NewCheckpoint(tt.store, "numbers")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			for v := range GeneratorFrom(ctx, cp, values...) {
+				if v.Value == 4 {
+					continue
+				}
+				if err := cp.Ack(v.Offset); err != nil {
+					t.Fatal(err)
+				}
+				if v.Value == 5 {
+					break
+				}
+			}
+			cancel()
+
+			// a restart resumes from the first value not acknowledged
+			cp, err = NewCheckpoint(tt.store, "numbers")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			var result []int
+			for v := range GeneratorFrom(ctx, cp, values...) {
+				result = append(result, v.Value)
+				if err := cp.Ack(v.Offset); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			AssertResults(t, result, []int{4, 5, 6, 7, 8, 9, 10})
+			if offset, _ := tt.store.Load("numbers"); offset != 10 {
+				t.Errorf("expected offset 10 to be saved, got %d", offset)
+			}
+		})
+	}
+}
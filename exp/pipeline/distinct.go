@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+// Seen records keys, reporting whether each has been seen before.
+type Seen[K comparable] interface {
+	Seen(K) bool
+}
+
+// Unique removes duplicate values from the input channel based on a key
+// function, unlike Distinct which only removes consecutive duplicates.
+// Memory is bounded by `seen`, see NewLRU, NewTTL and NewBloom.
+func Unique[T any, K comparable](ctx context.Context, in <-chan T, keyFn func(T) K, seen Seen[K]) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if seen.Seen(keyFn(v)) {
+					continue
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// LRU remembers the `n` most recently seen keys.
+type LRU[K comparable] struct {
+	n     int
+	mu    sync.Mutex
+	order *list.List
+	keys  map[K]*list.Element
+}
+
+// NewLRU returns a Seen that remembers the last `n` distinct keys, so a
+// duplicate is only removed if its key is among them.
+func NewLRU[K comparable](n int) *LRU[K] {
+	return &LRU[K]{n: n, order: list.New(), keys: make(map[K]*list.Element, n)}
+}
+
+// Seen reports whether the key is among the last `n` distinct keys and
+// records it as the most recent.
+func (l *LRU[K]) Seen(key K) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, found := l.keys[key]; found {
+		l.order.MoveToFront(e)
+		return true
+	}
+	l.keys[key] = l.order.PushFront(key)
+	if l.order.Len() > l.n {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.keys, oldest.Value.(K))
+	}
+	return false
+}
+
+// TTL remembers each key for a fixed duration after it was first seen.
+type TTL[K comparable] struct {
+	ttl   time.Duration
+	mu    sync.Mutex
+	keys  map[K]time.Time
+	queue []ttlEntry[K]
+}
+
+type ttlEntry[K comparable] struct {
+	key        K
+	expiration time.Time
+}
+
+// NewTTL returns a Seen that remembers each key for `ttl`, so a duplicate is
+// only removed if it arrives within `ttl` of the first value with its key.
+func NewTTL[K comparable](ttl time.Duration) *TTL[K] {
+	return &TTL[K]{ttl: ttl, keys: make(map[K]time.Time)}
+}
+
+// Seen reports whether the key was first seen less than `ttl` ago, recording
+// it otherwise.
+func (t *TTL[K]) Seen(key K) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	// keys expire in the order they were recorded, so sweep from the front
+	for len(t.queue) > 0 && !t.queue[0].expiration.After(now) {
+		if t.keys[t.queue[0].key] == t.queue[0].expiration {
+			delete(t.keys, t.queue[0].key)
+		}
+		t.queue = t.queue[1:]
+	}
+	if _, found := t.keys[key]; found {
+		return true
+	}
+	exp := now.Add(t.ttl)
+	t.keys[key] = exp
+	t.queue = append(t.queue, ttlEntry[K]{key: key, expiration: exp})
+	return false
+}
+
+// Bloom is a Bloom filter over string keys.
+type Bloom struct {
+	mu   sync.Mutex
+	bits []uint64
+	m    uint64
+	k    int
+	seed [2]maphash.Seed
+}
+
+// NewBloom returns a Seen sized for `n` keys with a false-positive rate of
+// `p`, using a fixed amount of memory however many keys are seen. A false
+// positive removes a value that was not a duplicate.
+func NewBloom(n int, p float64) *Bloom {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := max(int(math.Round(float64(m)/float64(n)*math.Ln2)), 1)
+	return &Bloom{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+		seed: [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+	}
+}
+
+// Seen reports whether the key has probably been seen before and records it.
+func (b *Bloom) Seen(key string) bool {
+	h1 := maphash.String(b.seed[0], key)
+	h2 := maphash.String(b.seed[1], key) | 1
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seen := true
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			seen = false
+			b.bits[word] |= mask
+		}
+	}
+	return seen
+}
@@ -0,0 +1,75 @@
+package pipeline_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func identity(val int) int { return val }
+
+func TestUniqueLRU(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 1, 3, 4, 1, 2, 5, 5)
+
+	// 2 has been forgotten by the time it repeats
+	output := Unique(ctx, input, identity, NewLRU[int](3))
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2, 3, 4, 2, 5})
+}
+
+func TestUniqueTTL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := make(chan int)
+	go func() {
+		defer close(input)
+		for _, v := range []int{1, 2, 1} {
+			input <- v
+		}
+		time.Sleep(60 * time.Millisecond)
+		for _, v := range []int{1, 3, 3} {
+			input <- v
+		}
+	}()
+
+	output := Unique(ctx, input, identity, NewTTL[int](50*time.Millisecond))
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2, 1, 3})
+}
+
+func TestUniqueBloom(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	values := make([]int, 0, 2000)
+	for i := range 1000 {
+		values = append(values, i, i)
+	}
+	input := Generator(ctx, values...)
+
+	output := Unique(ctx, input, strconv.Itoa, NewBloom(1000, 0.01))
+	n := 0
+	for range output {
+		n++
+	}
+
+	// allow for false positives well beyond the configured 1%
+	if n > 1000 || n < 950 {
+		t.Errorf("expected close to 1000 unique values, got %d", n)
+	}
+}
@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// Drain coordinates a two-phase shutdown of a pipeline. Sources stop
+// accepting input first, letting the values already in flight drain through
+// the remaining stages to the sink, and only once the deadline has passed are
+// the stages themselves canceled.
+type Drain struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	input       context.Context
+	cancelInput context.CancelFunc
+}
+
+// WithDrain returns a Drain derived from the parent context.
+func WithDrain(parent context.Context) *Drain {
+	d := &Drain{}
+	d.ctx, d.cancel = context.WithCancel(parent)
+	d.input, d.cancelInput = context.WithCancel(d.ctx)
+	return d
+}
+
+// Context returns the context to pass to the stages of the pipeline, which is
+// canceled at the end of Shutdown.
+func (d *Drain) Context() context.Context {
+	return d.ctx
+}
+
+// Input returns the context to pass to sources such as Generator, which is
+// canceled at the start of Shutdown. Channel sources can be wrapped with Until.
+func (d *Drain) Input() context.Context {
+	return d.input
+}
+
+// Shutdown stops the pipeline accepting input and waits for `done` to be
+// closed, typically by the sink once it has received everything, or for `ctx`
+// to be done, after which the stages are canceled. It returns the context's
+// error if the pipeline did not drain in time.
+func (d *Drain) Shutdown(ctx context.Context, done <-chan struct{}) error {
+	d.cancelInput()
+	defer d.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Until forwards values from the input channel to the output channel until
+// `stop` is closed, then closes the output channel so the stages after it can
+// drain. Values left on the input channel are not read.
+func Until[T any](ctx context.Context, stop <-chan struct{}, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
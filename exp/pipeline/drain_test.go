@@ -0,0 +1,93 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestDrain(t *testing.T) {
+	d := WithDrain(context.Background())
+	ctx := d.Context()
+
+	// an endless source that stops when input is no longer accepted
+	var sent atomic.Int64
+	source := make(chan int)
+	go func() {
+		for i := 1; ; i++ {
+			select {
+			case source <- i:
+				sent.Add(1)
+				time.Sleep(5 * time.Millisecond)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	input := Until(ctx, d.Input().Done(), source)
+	mapped := Map(ctx, 0, input, func(ctx context.Context, id int, val int) int {
+		time.Sleep(20 * time.Millisecond)
+		return 1
+	})
+	chunks := Chunk(ctx, mapped, 4)
+	output := Reduce(ctx, chunks, func(acc int, val []int) int {
+		return acc + len(val)
+	}, 0, 0)
+
+	var result int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result = <-output
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	shutdown, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Shutdown(shutdown, done); err != nil {
+		t.Fatalf("expected the pipeline to drain, got %v", err)
+	}
+
+	if n := sent.Load(); n == 0 || int64(result) != n {
+		t.Errorf("expected all %d values sent to be reduced, got %d", n, result)
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	d := WithDrain(context.Background())
+	ctx := d.Context()
+
+	// a stage that never finishes its in-flight value
+	started := make(chan struct{})
+	input := Generator(d.Input(), 1, 2, 3)
+	output := Map(ctx, 0, input, func(ctx context.Context, id int, val int) int {
+		close(started)
+		<-ctx.Done()
+		return val
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range output {
+		}
+	}()
+
+	<-started
+	start := time.Now()
+	shutdown, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(shutdown, done); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	AssertExecutionTime(t, start, 50*time.Millisecond, 10*time.Millisecond)
+
+	<-done
+	if ctx.Err() == nil {
+		t.Errorf("expected the stages to be canceled")
+	}
+}
@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// Group is a sub-stream of the values sharing a key.
+type Group[K comparable, T any] struct {
+	Key    K
+	Values <-chan T
+}
+
+// GroupBy fans values from the input channel out into a sub-stream per key,
+// sending each new group to the output channel the first time its key is seen.
+// Every group must be drained, as a blocked group holds back all the others.
+// All groups are closed when the input channel is closed or the context is canceled.
+func GroupBy[T any, K comparable](ctx context.Context, in <-chan T, keyFn func(T) K) <-chan Group[K, T] {
+	out := make(chan Group[K, T])
+	go func() {
+		groups := make(map[K]chan T)
+		defer func() {
+			for _, ch := range groups {
+				close(ch)
+			}
+			close(out)
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				k := keyFn(v)
+				ch, found := groups[k]
+				if !found {
+					ch = make(chan T)
+					groups[k] = ch
+					select {
+					case out <- Group[K, T]{Key: k, Values: ch}:
+					case <-ctx.Done():
+						return
+					}
+				}
+				select {
+				case ch <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// KeyValue pairs a key with its current value.
+type KeyValue[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// ReduceByKey applies a reducing function to the values from the input channel
+// per key, starting each key from `initial`, and sends a single map of the
+// results when the input channel is closed.
+func ReduceByKey[K comparable, L, R any](ctx context.Context, in <-chan R, keyFn func(R) K, fn func(L, R) L, initial L) <-chan map[K]L {
+	out := make(chan map[K]L)
+	go func() {
+		defer close(out)
+		acc := make(map[K]L)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					select {
+					case out <- acc:
+					case <-ctx.Done():
+					}
+					return
+				}
+				k := keyFn(v)
+				l, found := acc[k]
+				if !found {
+					l = initial
+				}
+				acc[k] = fn(l, v)
+			}
+		}
+	}()
+	return out
+}
+
+// ScanByKey is the incremental form of ReduceByKey, sending the updated
+// result for a key after every value rather than a map at the end.
+func ScanByKey[K comparable, L, R any](ctx context.Context, in <-chan R, keyFn func(R) K, fn func(L, R) L, initial L) <-chan KeyValue[K, L] {
+	out := make(chan KeyValue[K, L])
+	go func() {
+		defer close(out)
+		acc := make(map[K]L)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				k := keyFn(v)
+				l, found := acc[k]
+				if !found {
+					l = initial
+				}
+				l = fn(l, v)
+				acc[k] = l
+				select {
+				case out <- KeyValue[K, L]{Key: k, Value: l}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
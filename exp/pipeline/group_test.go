@@ -0,0 +1,83 @@
+package pipeline_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func parity(val int) string {
+	if val%2 == 0 {
+		return "even"
+	}
+	return "odd"
+}
+
+func TestGroupBy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	output := GroupBy(ctx, input, parity)
+	var mu sync.Mutex
+	results := make(map[string][]int)
+	var wg sync.WaitGroup
+	for g := range output {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for val := range g.Values {
+				mu.Lock()
+				results[g.Key] = append(results[g.Key], val)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	AssertResults(t, results["odd"], []int{1, 3, 5, 7, 9})
+	AssertResults(t, results["even"], []int{2, 4, 6, 8, 10})
+}
+
+func TestReduceByKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	output := ReduceByKey(ctx, input, parity, func(acc int, val int) int {
+		return acc + val
+	}, 0)
+	result := <-output
+
+	if result["odd"] != 25 || result["even"] != 30 {
+		t.Errorf("expected odd=25 even=30, got %v", result)
+	}
+}
+
+func TestScanByKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5)
+
+	output := ScanByKey(ctx, input, parity, func(acc int, val int) int {
+		return acc + 1
+	}, 0)
+	var keys []string
+	var result []int
+	for kv := range output {
+		keys = append(keys, kv.Key)
+		result = append(result, kv.Value)
+	}
+
+	if !slices.Equal(keys, []string{"odd", "even", "odd", "even", "odd"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	AssertResults(t, result, []int{1, 1, 2, 2, 3})
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"context"
+	"iter"
+)
+
+// FromSeq sends the values of an iterator to a channel.
+// It stops iterating when the context is canceled.
+func FromSeq[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range seq {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// FromSeq2 sends the pairs of an iterator to a channel.
+// It stops iterating when the context is canceled.
+func FromSeq2[K comparable, V any](ctx context.Context, seq iter.Seq2[K, V]) <-chan KeyValue[K, V] {
+	out := make(chan KeyValue[K, V])
+	go func() {
+		defer close(out)
+		for k, v := range seq {
+			select {
+			case out <- KeyValue[K, V]{Key: k, Value: v}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ToSeq returns an iterator over the values received from a channel.
+// Stopping the iteration early leaves the channel unread, so the context of
+// the stages feeding it should be canceled.
+func ToSeq[T any](in <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range in {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package pipeline_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestFromSeq(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	output := FromSeq(ctx, slices.Values([]int{1, 2, 3, 4, 5}))
+
+	AssertResults(t, slices.Collect(ToSeq(output)), []int{1, 2, 3, 4, 5})
+}
+
+func TestFromSeq2(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	output := FromSeq2(ctx, maps.All(map[string]int{"a": 1, "b": 2, "c": 3}))
+	var result []int
+	for kv := range output {
+		result = append(result, kv.Value)
+	}
+	slices.Sort(result)
+
+	AssertResults(t, result, []int{1, 2, 3})
+}
+
+func TestToSeq(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5)
+	var result []int
+	for val := range ToSeq(input) {
+		if val > 3 {
+			break
+		}
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2, 3})
+}
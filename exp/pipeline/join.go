@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+)
+
+// JoinKind determines which values Join sends.
+type JoinKind int
+
+const (
+	// InnerJoin sends only matched pairs.
+	InnerJoin JoinKind = iota
+	// LeftJoin also sends left values that were never matched.
+	LeftJoin
+)
+
+// Joined is a pair of values with the same key. Matched is false for a left
+// value that found no right value within the window, in which case Right is
+// the zero value.
+type Joined[L, R any] struct {
+	Left    L
+	Right   R
+	Matched bool
+}
+
+type joinEntry[T any] struct {
+	v       T
+	at      time.Time
+	matched bool
+}
+
+// Join correlates values from two input channels by key, pairing each value
+// with every value from the other channel that has the same key and arrived
+// within `window` of it. Values are evicted once they are older than
+// `window`; with LeftJoin an evicted left value that was never matched is
+// sent as an orphan, as are any left unmatched when both channels are closed.
+func Join[L, R any, K comparable](ctx context.Context, left <-chan L, right <-chan R, leftKey func(L) K, rightKey func(R) K, window time.Duration, kind JoinKind) <-chan Joined[L, R] {
+	out := make(chan Joined[L, R])
+	go func() {
+		defer close(out)
+		lefts := make(map[K][]*joinEntry[L])
+		rights := make(map[K][]*joinEntry[R])
+		ticker := time.NewTicker(max(window/4, time.Millisecond))
+		defer ticker.Stop()
+
+		send := func(j Joined[L, R]) bool {
+			select {
+			case out <- j:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		// evict removes left values older than the cutoff, sending orphans if
+		// required; a zero cutoff evicts everything.
+		evict := func(cutoff time.Time) bool {
+			for k, entries := range lefts {
+				kept := entries[:0]
+				for _, e := range entries {
+					if !cutoff.IsZero() && e.at.After(cutoff) {
+						kept = append(kept, e)
+						continue
+					}
+					if kind == LeftJoin && !e.matched && !send(Joined[L, R]{Left: e.v}) {
+						return false
+					}
+				}
+				if len(kept) == 0 {
+					delete(lefts, k)
+				} else {
+					lefts[k] = kept
+				}
+			}
+			for k, entries := range rights {
+				kept := entries[:0]
+				for _, e := range entries {
+					if !cutoff.IsZero() && e.at.After(cutoff) {
+						kept = append(kept, e)
+					}
+				}
+				if len(kept) == 0 {
+					delete(rights, k)
+				} else {
+					rights[k] = kept
+				}
+			}
+			return true
+		}
+
+		for left != nil || right != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !evict(now.Add(-window)) {
+					return
+				}
+			case v, ok := <-left:
+				if !ok {
+					left = nil
+					continue
+				}
+				k := leftKey(v)
+				e := &joinEntry[L]{v: v, at: time.Now()}
+				lefts[k] = append(lefts[k], e)
+				for _, r := range rights[k] {
+					e.matched = true
+					if !send(Joined[L, R]{Left: v, Right: r.v, Matched: true}) {
+						return
+					}
+				}
+			case v, ok := <-right:
+				if !ok {
+					right = nil
+					continue
+				}
+				k := rightKey(v)
+				rights[k] = append(rights[k], &joinEntry[R]{v: v, at: time.Now()})
+				for _, l := range lefts[k] {
+					l.matched = true
+					if !send(Joined[L, R]{Left: l.v, Right: v, Matched: true}) {
+						return
+					}
+				}
+			}
+		}
+		evict(time.Time{})
+	}()
+	return out
+}
@@ -0,0 +1,70 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+type order struct {
+	id    int
+	total int
+}
+
+type payment struct {
+	order  int
+	amount int
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    JoinKind
+		matched []int
+		orphans []int
+	}{
+		{"inner", InnerJoin, []int{1, 3, 3}, nil},
+		{"left", LeftJoin, []int{1, 3, 3}, []int{2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			orders := make(chan order)
+			payments := make(chan payment)
+			go func() {
+				defer close(orders)
+				defer close(payments)
+				orders <- order{1, 10}
+				payments <- payment{1, 10}
+				orders <- order{2, 20}
+				payments <- payment{3, 5}
+				orders <- order{3, 30}
+				payments <- payment{3, 25}
+				// order 2 has been evicted by the time its payment arrives
+				time.Sleep(100 * time.Millisecond)
+				payments <- payment{2, 20}
+				orders <- order{4, 40}
+			}()
+
+			output := Join(ctx, orders, payments, func(o order) int { return o.id }, func(p payment) int { return p.order }, 50*time.Millisecond, tt.kind)
+			var matched, orphans []int
+			for j := range output {
+				if j.Matched {
+					if j.Left.id != j.Right.order {
+						t.Errorf("mismatched pair %v", j)
+					}
+					matched = append(matched, j.Left.id)
+				} else {
+					orphans = append(orphans, j.Left.id)
+				}
+			}
+
+			AssertResults(t, matched, tt.matched)
+			AssertResults(t, orphans, tt.orphans)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy determines what a stage does with a value when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// Block stops receiving from the input channel until there is room.
+	Block OverflowPolicy = iota
+	// DropNewest discards the value that has just been received.
+	DropNewest
+	// DropOldest discards the value that has been buffered the longest.
+	DropOldest
+	// Overflow sends the value to a separate overflow channel.
+	Overflow
+)
+
+// String returns the string representation of the policy.
+func (p OverflowPolicy) String() string {
+	var names = []string{
+		"Block",
+		"DropNewest",
+		"DropOldest",
+		"Overflow",
+	}
+	if Block <= p && p <= Overflow {
+		return names[p]
+	}
+	return fmt.Sprintf("%%!OverflowPolicy(%d)", p)
+}
+
+// TokenBucket limits the rate at which values pass from the input channel to
+// the output channel using a token bucket that refills smoothly at `limit`
+// tokens `per` duration and holds at most `burst` tokens, starting full.
+// Values waiting for a token are buffered, up to `size`; once the buffer is
+// full `policy` decides what happens to the next value. With Overflow those
+// values are sent to the second channel, which must then be drained.
+// The returned function reports the count of values discarded by DropNewest
+// or DropOldest. A `limit`, `burst` or `size` below 1 is treated as 1, and
+// tokens are added at most once a nanosecond, even if `per` is 0 or less.
+func TokenBucket[T any](ctx context.Context, in <-chan T, limit int, per time.Duration, burst, size int, policy OverflowPolicy, opts ...Option) (<-chan T, <-chan T, func() uint64) {
+	o := newOptions(opts)
+	if limit < 1 {
+		limit = 1
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan T)
+	overflow := make(chan T)
+	dropped := new(atomic.Uint64)
+	// a zero interval would make the refill 0/0, leaving tokens NaN
+	interval := max(per/time.Duration(limit), time.Nanosecond)
+	go func() {
+		defer close(out)
+		defer close(overflow)
+		buffer := make([]T, 0, size)
+		tokens := float64(burst)
+		timer := o.clock.NewTimer(interval)
+		timer.Stop()
+		defer timer.Stop()
+		last := o.clock.Now()
+		for {
+			now := o.clock.Now()
+			tokens = min(float64(burst), tokens+float64(now.Sub(last))/float64(interval))
+			last = now
+
+			var send chan<- T
+			var next T
+			var wait <-chan time.Time
+			if len(buffer) > 0 {
+				if tokens >= 1 {
+					send, next = out, buffer[0]
+				} else {
+					timer.Reset(time.Duration((1 - tokens) * float64(interval)))
+					wait = timer.C()
+				}
+			}
+			if wait == nil {
+				timer.Stop()
+			}
+			recv := in
+			if recv == nil && len(buffer) == 0 {
+				return
+			}
+			if policy == Block && len(buffer) >= size {
+				recv = nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case send <- next:
+				buffer = buffer[1:]
+				tokens--
+			case <-wait:
+			case v, ok := <-recv:
+				if !ok {
+					in = nil
+					continue
+				}
+				if len(buffer) < size {
+					buffer = append(buffer, v)
+					continue
+				}
+				switch policy {
+				case DropNewest:
+					dropped.Add(1)
+				case DropOldest:
+					buffer = append(buffer[1:], v)
+					dropped.Add(1)
+				case Overflow:
+					select {
+					case overflow <- v:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out, overflow, dropped.Load
+}
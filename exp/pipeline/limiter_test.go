@@ -0,0 +1,135 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmilhench/x/exp/clock"
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestTokenBucket(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	input := Generator(ctx, 1, 2, 3, 4, 5, 6)
+
+	// a burst of 2 then one every 100ms
+	output, _, dropped := TokenBucket(ctx, input, 10, time.Second, 2, 10, Block)
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertExecutionTime(t, start, 400*time.Millisecond, 20*time.Millisecond)
+	AssertResults(t, result, []int{1, 2, 3, 4, 5, 6})
+	if n := dropped(); n != 0 {
+		t.Errorf("expected nothing dropped, got %d", n)
+	}
+}
+
+func TestTokenBucketPolicies(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		want     []int
+		overflow []int
+		dropped  uint64
+	}{
+		{Block, []int{1, 2, 3, 4, 5}, nil, 0},
+		{DropNewest, []int{1, 2}, nil, 3},
+		{DropOldest, []int{4, 5}, nil, 3},
+		{Overflow, []int{1, 2}, []int{3, 4, 5}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			input := Generator(ctx, 1, 2, 3, 4, 5)
+
+			output, overflow, dropped := TokenBucket(ctx, input, 1, 20*time.Millisecond, 1, 2, tt.policy)
+			done := make(chan []int)
+			go func() {
+				var result []int
+				for val := range overflow {
+					result = append(result, val)
+				}
+				done <- result
+			}()
+			// let the input fill the buffer before consuming
+			time.Sleep(50 * time.Millisecond)
+			var result []int
+			for val := range output {
+				result = append(result, val)
+			}
+
+			AssertResults(t, result, tt.want)
+			AssertResults(t, <-done, tt.overflow)
+			if n := dropped(); n != tt.dropped {
+				t.Errorf("expected %d dropped, got %d", tt.dropped, n)
+			}
+		})
+	}
+}
+
+func TestTokenBucketLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2)
+
+	// a limit of 0 is treated as 1 rather than dividing by zero
+	output, _, _ := TokenBucket(ctx, input, 0, 20*time.Millisecond, 2, 2, Block)
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2})
+}
+
+func TestTokenBucketWithClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	input := Generator(ctx, 1, 2, 3)
+
+	// one token every 100ms, starting with one
+	output, _, _ := TokenBucket(ctx, input, 1, 100*time.Millisecond, 1, 10, Block, WithClock(clk))
+	result := []int{<-output}
+	for range 2 {
+		clk.BlockUntil(1)
+		clk.Advance(100 * time.Millisecond)
+		result = append(result, <-output)
+	}
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2, 3})
+}
+
+func TestTokenBucketInterval(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	input := Generator(ctx, 1, 2, 3)
+
+	// a `per` of 0 adds a token every nanosecond rather than none at all
+	output, _, _ := TokenBucket(ctx, input, 1, 0, 1, 10, Block, WithClock(clk))
+	result := []int{<-output}
+	for range 2 {
+		clk.BlockUntil(1)
+		clk.Advance(time.Nanosecond)
+		result = append(result, <-output)
+	}
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2, 3})
+}
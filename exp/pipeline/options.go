@@ -0,0 +1,28 @@
+package pipeline
+
+import (
+	"github.com/cmilhench/x/exp/clock"
+)
+
+// Option configures optional behaviour of a stage.
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock makes a time based stage use `c` rather than the real clock,
+// for example a clock.Fake in tests.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
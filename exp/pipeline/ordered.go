@@ -0,0 +1,86 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// OrderedMap applies a function to values received from an input channel on
+// `workerCount` goroutines, sending the results to the output channel in the
+// same order the values arrived on `in`.
+// At most `buffer` results are held waiting for a slower predecessor, so a
+// slow item applies backpressure instead of growing memory without limit;
+// `buffer` is raised to `workerCount` if smaller so every worker can be busy.
+// The function stops when the input channel is closed or the context is canceled.
+func OrderedMap[I, O any](ctx context.Context, in <-chan I, workerCount, buffer int, fn func(context.Context, I) O) <-chan O {
+	if workerCount < 1 {
+		workerCount = 1
+	}
+	if buffer < workerCount {
+		buffer = workerCount
+	}
+	type job struct {
+		v   I
+		res chan O
+	}
+	out := make(chan O)
+	jobs := make(chan job)
+	pending := make(chan chan O, buffer)
+
+	// Dispatch values in arrival order, reserving a slot in `pending` for each.
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				j := job{v: v, res: make(chan O, 1)}
+				select {
+				case pending <- j.res:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case jobs <- j:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.res <- fn(ctx, j.v)
+			}
+		}()
+	}
+
+	// Emit results in the order their slots were reserved.
+	go func() {
+		defer close(out)
+		defer wg.Wait()
+		for res := range pending {
+			select {
+			case o := <-res:
+				select {
+				case out <- o:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
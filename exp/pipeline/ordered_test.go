@@ -0,0 +1,73 @@
+package pipeline_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+func TestOrderedMap(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	input := Generator(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	var inflight, peak atomic.Int32
+	output := OrderedMap(ctx, input, 4, 4, func(ctx context.Context, val int) int {
+		n := inflight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		// later values finish sooner so the results arrive out of order
+		time.Sleep(time.Duration(11-val) * 20 * time.Millisecond)
+		inflight.Add(-1)
+		return val * 2
+	})
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertExecutionTime(t, start, 300*time.Millisecond, 100*time.Millisecond)
+	AssertResults(t, result, []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20})
+	if p := peak.Load(); p > 4 {
+		t.Errorf("expected at most 4 values in flight, got %d", p)
+	}
+}
+
+func TestOrderedMapBuffer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	var started atomic.Int32
+	release := make(chan struct{})
+	output := OrderedMap(ctx, input, 2, 3, func(ctx context.Context, val int) int {
+		started.Add(1)
+		if val == 1 {
+			<-release
+		}
+		return val
+	})
+
+	// while the first value is stuck no more than `buffer` values may be taken
+	time.Sleep(100 * time.Millisecond)
+	if n := started.Load(); n > 4 {
+		t.Errorf("expected the reorder buffer to bound work, %d values started", n)
+	}
+	close(release)
+
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+	AssertResults(t, result, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+}
@@ -204,11 +204,12 @@ func FanOut[T, O any](ctx context.Context, in <-chan T, workerCount int, fn func
 
 // Throttle limits the rate at which values are sent from the input channel to the output channel.
 // Only one value is sent every `rate` duration.
-func Throttle[T any](ctx context.Context, in <-chan T, rate time.Duration) <-chan T {
+func Throttle[T any](ctx context.Context, in <-chan T, rate time.Duration, opts ...Option) <-chan T {
+	o := newOptions(opts)
 	out := make(chan T)
 	go func() {
 		defer close(out)
-		ticker := time.NewTicker(rate)
+		ticker := o.clock.NewTicker(rate)
 		defer ticker.Stop()
 		for {
 			select {
@@ -220,7 +221,7 @@ func Throttle[T any](ctx context.Context, in <-chan T, rate time.Duration) <-cha
 				}
 				select {
 				case out <- v:
-					<-ticker.C
+					<-ticker.C()
 				case <-ctx.Done():
 					return
 				}
@@ -271,11 +272,12 @@ func Partition[T any](ctx context.Context, id int, in <-chan T, fn func(context.
 // frequency of event processing but may process more than one event in each interval),
 // time-based sampling captures exactly one event at the specified interval, regardless
 // of how many events pass through during that time.
-func Sample[T any](ctx context.Context, in <-chan T, rate time.Duration) <-chan T {
+func Sample[T any](ctx context.Context, in <-chan T, rate time.Duration, opts ...Option) <-chan T {
+	o := newOptions(opts)
 	out := make(chan T)
 	go func() {
 		defer close(out)
-		ticker := time.NewTicker(rate)
+		ticker := o.clock.NewTicker(rate)
 		defer ticker.Stop()
 		for {
 			select {
@@ -286,7 +288,7 @@ func Sample[T any](ctx context.Context, in <-chan T, rate time.Duration) <-chan
 					return
 				}
 				select {
-				case <-ticker.C:
+				case <-ticker.C():
 					select {
 					case out <- v: // out ← %+v
 					case <-ctx.Done(): // canceled?
@@ -361,13 +363,20 @@ func Chunk[T any](ctx context.Context, in <-chan T, size int) <-chan []T {
 			case v, ok := <-in:
 				if !ok {
 					if len(chunk) > 0 {
-						out <- chunk
+						select {
+						case out <- chunk:
+						case <-ctx.Done():
+						}
 					}
 					return
 				}
 				chunk = append(chunk, v)
 				if len(chunk) == size {
-					out <- chunk
+					select {
+					case out <- chunk:
+					case <-ctx.Done():
+						return
+					}
 					chunk = make([]T, 0, size)
 				}
 			}
@@ -376,6 +385,57 @@ func Chunk[T any](ctx context.Context, in <-chan T, size int) <-chan []T {
 	return out //, nil
 }
 
+// Batch groups values from the input channel into slices of up to `maxSize`
+// and sends each batch to the output channel when it is full or when `maxWait`
+// has passed since its first value arrived, whichever comes first.
+// The partial batch is sent when the input channel is closed.
+func Batch[T any](ctx context.Context, in <-chan T, maxSize int, maxWait time.Duration) <-chan []T {
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(maxWait)
+		timer.Stop()
+		defer timer.Stop()
+		batch := make([]T, 0, maxSize)
+		flush := func() bool {
+			timer.Stop()
+			if len(batch) == 0 {
+				return true
+			}
+			select {
+			case out <- batch:
+			case <-ctx.Done():
+				return false
+			}
+			batch = make([]T, 0, maxSize)
+			return true
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				if !flush() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if len(batch) == 0 {
+					timer.Reset(maxWait)
+				}
+				batch = append(batch, v)
+				if len(batch) >= maxSize && !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
 // Window groups incoming values into overlapping windows of a specified size and slide.
 func Window[T any](ctx context.Context, in <-chan T, size int, slide int) <-chan []T {
 	out := make(chan []T)
@@ -436,11 +496,15 @@ func Distinct[T any, K comparable](ctx context.Context, in <-chan T, keyFn func(
 
 // RateLimiter limits the number of values that can pass through in a given time period buffering up values.
 // if the buffer exceeds `size` the value passing though is dropped.
-func RateLimiter[T any](ctx context.Context, in <-chan T, limit int, per time.Duration, size int) <-chan T {
+//
+// Deprecated: use TokenBucket, which refills smoothly rather than all at
+// once each period and reports or overflows the values it drops.
+func RateLimiter[T any](ctx context.Context, in <-chan T, limit int, per time.Duration, size int, opts ...Option) <-chan T {
+	o := newOptions(opts)
 	out := make(chan T)
 	go func() {
 		defer close(out)
-		ticker := time.NewTicker(per)
+		ticker := o.clock.NewTicker(per)
 		defer ticker.Stop()
 		var buffer []T
 		var tokens = limit
@@ -449,21 +513,22 @@ func RateLimiter[T any](ctx context.Context, in <-chan T, limit int, per time.Du
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
+			case <-ticker.C():
 				tokens = limit
 			case v, ok := <-in:
 				if !ok {
 					break outer
 				}
 				if tokens > 0 {
+					next := v
 					if len(buffer) > 0 {
-						out <- buffer[0]
-						buffer = buffer[1:]
-						if len(buffer) < size {
-							buffer = append(buffer, v)
-						}
-					} else {
-						out <- v
+						next = buffer[0]
+						buffer = append(buffer[1:], v)
+					}
+					select {
+					case out <- next:
+					case <-ctx.Done():
+						return
 					}
 					tokens--
 				} else {
@@ -474,22 +539,22 @@ func RateLimiter[T any](ctx context.Context, in <-chan T, limit int, per time.Du
 			}
 		}
 		// drain the buffer
-		for {
+		for len(buffer) > 0 {
+			if tokens == 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C():
+					tokens = limit
+				}
+				continue
+			}
 			select {
+			case out <- buffer[0]:
+				buffer = buffer[1:]
+				tokens--
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				tokens = limit
-			default:
-				if tokens > 0 {
-					if len(buffer) > 0 {
-						out <- buffer[0]
-						buffer = buffer[1:]
-						tokens--
-					} else {
-						return
-					}
-				}
 			}
 		}
 	}()
@@ -516,10 +581,12 @@ func Reduce[L, R any](ctx context.Context, in <-chan R, fn func(L, R) L, initial
 				any = true
 			}
 		}
-		if any {
-			out <- acc
-		} else {
-			out <- zero
+		if !any {
+			acc = zero
+		}
+		select {
+		case out <- acc:
+		case <-ctx.Done():
 		}
 	}()
 	return out
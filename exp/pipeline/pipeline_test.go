@@ -7,14 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cmilhench/x/exp/clock"
 	. "github.com/cmilhench/x/exp/pipeline"
 )
 
-type Result[T any] struct {
-	Out T
-	Err error
-}
-
 func Input(t *testing.T) <-chan int {
 	t.Helper()
 	out := make(chan int)
@@ -240,6 +236,32 @@ func TestThrottle(t *testing.T) {
 	AssertResults(t, result, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
 }
 
+func TestThrottleWithClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	input := Generator(ctx, 1, 2, 3)
+	output := Throttle(ctx, input, 100*time.Millisecond, WithClock(clk))
+
+	clk.BlockUntil(1)
+	result := []int{<-output}
+	// nothing more is sent until the next tick
+	select {
+	case val := <-output:
+		t.Errorf("expected no value before the tick, got %d", val)
+	case <-time.After(20 * time.Millisecond):
+	}
+	for range 3 {
+		clk.Advance(100 * time.Millisecond)
+		if val, ok := <-output; ok {
+			result = append(result, val)
+		}
+	}
+
+	AssertResults(t, result, []int{1, 2, 3})
+}
+
 func TestPartition(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -294,6 +316,29 @@ func TestSample(t *testing.T) {
 	AssertResults(t, result, []int{1, 2, 3, 8, 9})
 }
 
+func TestSampleWithClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	input := make(chan int)
+	output := Sample(ctx, input, 100*time.Millisecond, WithClock(clk))
+
+	// only the first value after each tick is sent
+	clk.BlockUntil(1)
+	clk.Advance(100 * time.Millisecond)
+	input <- 1
+	result := []int{<-output}
+	input <- 2
+	input <- 3
+	close(input)
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1})
+}
+
 func TestBroadcast(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -345,6 +390,31 @@ func TestChunk(t *testing.T) {
 	AssertResults(t, result[3], []int{10})
 }
 
+func TestBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	input := Input(t)
+
+	output := Batch(ctx, input, 3, 200*time.Millisecond)
+	var result [][]int
+	for vals := range output {
+		result = append(result, vals)
+	}
+
+	AssertExecutionTime(t, start, 2000*time.Millisecond, 10*time.Millisecond)
+	if len(result) != 6 {
+		t.Fatalf("expected 6 batches, got %d", len(result))
+	}
+	AssertResults(t, result[0], []int{1})
+	AssertResults(t, result[1], []int{2})
+	AssertResults(t, result[2], []int{3, 4, 5})
+	AssertResults(t, result[3], []int{6, 7})
+	AssertResults(t, result[4], []int{8})
+	AssertResults(t, result[5], []int{9, 10})
+}
+
 func TestWindow(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -398,6 +468,51 @@ func TestRateLimiter(t *testing.T) {
 	AssertResults(t, result, []int{1, 2, 3, 4, 5, 6, 7, 9, 10})
 }
 
+func TestRateLimiterWithClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	input := make(chan int)
+	output := RateLimiter(ctx, input, 2, 100*time.Millisecond, 1, WithClock(clk))
+
+	clk.BlockUntil(1)
+	// two tokens pass 1 and 2, 3 is buffered and 4 dropped
+	input <- 1
+	result := []int{<-output}
+	input <- 2
+	result = append(result, <-output)
+	input <- 3
+	input <- 4
+	select {
+	case val := <-output:
+		t.Errorf("expected no value before the tick, got %d", val)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(100 * time.Millisecond)
+	close(input)
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2, 3})
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input := make(chan int)
+	output := RateLimiter(ctx, input, 2, time.Hour, 1)
+	// nothing reads 1, so the stage must stop waiting to send it when canceled
+	input <- 1
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	if val, ok := <-output; ok {
+		t.Errorf("expected the output to close when canceled, got %d", val)
+	}
+}
+
 func TestReduce(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
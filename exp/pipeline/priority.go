@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"context"
+	"reflect"
+)
+
+// PriorityFanIn combines multiple input channels into a single output channel,
+// always sending a waiting value from an earlier channel before one from a
+// later channel, so the first channel has the highest priority.
+// The function stops when all input channels are closed and all values have been read.
+func PriorityFanIn[T any](ctx context.Context, in ...<-chan T) <-chan T {
+	return WeightedFanIn(ctx, nil, in...)
+}
+
+// WeightedFanIn combines multiple input channels into a single output channel
+// in priority order like PriorityFanIn, but while values are waiting on
+// several channels each channel sends at most its weight in values per round,
+// so lower priority channels are not starved. A nil `weights` gives strict
+// priority.
+func WeightedFanIn[T any](ctx context.Context, weights []int, in ...<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		open := len(in)
+		inputs := make([]<-chan T, len(in))
+		copy(inputs, in)
+		credits := make([]int, len(in))
+		refill := func() {
+			for i := range credits {
+				credits[i] = 1
+				if i < len(weights) {
+					credits[i] = max(weights[i], 1)
+				}
+			}
+		}
+		refill()
+		cases := make([]reflect.SelectCase, len(in)+1)
+		cases[len(in)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+
+		// poll returns the index of the highest priority channel with a value
+		// waiting and credit to send it, or -1.
+		poll := func() (int, T, bool) {
+			for i, c := range inputs {
+				if c == nil || (weights != nil && credits[i] == 0) {
+					continue
+				}
+				select {
+				case v, ok := <-c:
+					return i, v, ok
+				default:
+				}
+			}
+			var zero T
+			return -1, zero, false
+		}
+
+		for open > 0 {
+			i, v, ok := poll()
+			if i < 0 && weights != nil {
+				refill()
+				i, v, ok = poll()
+			}
+			if i < 0 {
+				// nothing waiting, block until any channel is ready
+				for j, c := range inputs {
+					cases[j] = reflect.SelectCase{Dir: reflect.SelectRecv}
+					if c != nil {
+						cases[j].Chan = reflect.ValueOf(c)
+					}
+				}
+				chosen, rv, rok := reflect.Select(cases)
+				if chosen == len(in) {
+					return
+				}
+				i, ok = chosen, rok
+				if rok {
+					// a nil interface value has no dynamic type to assert
+					v, _ = rv.Interface().(T)
+				}
+			}
+			if !ok {
+				inputs[i] = nil
+				open--
+				continue
+			}
+			credits[i]--
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,97 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+// buffered returns a closed channel already holding the values.
+func buffered(values ...int) <-chan int {
+	out := make(chan int, len(values))
+	for _, v := range values {
+		out <- v
+	}
+	close(out)
+	return out
+}
+
+func TestPriorityFanIn(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	low := buffered(20, 21, 22)
+	high := buffered(10, 11, 12)
+
+	output := PriorityFanIn(ctx, high, low)
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{10, 11, 12, 20, 21, 22})
+}
+
+func TestPriorityFanInWaits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	high := make(chan int)
+	low := make(chan int)
+	go func() {
+		defer close(high)
+		defer close(low)
+		low <- 20
+		high <- 10
+	}()
+
+	output := PriorityFanIn(ctx, high, low)
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{20, 10})
+}
+
+func TestWeightedFanIn(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	high := buffered(10, 11, 12, 13, 14, 15)
+	low := buffered(20, 21, 22)
+
+	output := WeightedFanIn(ctx, []int{2, 1}, high, low)
+	var result []int
+	for val := range output {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{10, 11, 20, 12, 13, 21, 14, 15, 22})
+}
+
+func TestPriorityFanInNil(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	high := make(chan error)
+	low := make(chan error)
+	output := PriorityFanIn(ctx, high, low)
+	// sent while the stage is blocked waiting on every channel
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		low <- nil
+		close(low)
+		close(high)
+	}()
+
+	var result []error
+	for err := range output {
+		result = append(result, err)
+	}
+	if len(result) != 1 || result[0] != nil {
+		t.Errorf("expected a single nil error, got %v", result)
+	}
+}
@@ -0,0 +1,190 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// Result carries the output of a stage function together with the error it
+// returned, so failures can flow through a pipeline alongside values.
+type Result[T any] struct {
+	Out T
+	Err error
+}
+
+// ErrorPolicy determines what Errors does when it receives a failed result.
+type ErrorPolicy int
+
+const (
+	// StopOnError cancels the pipeline on the first failed result.
+	StopOnError ErrorPolicy = iota
+	// DeadLetter routes failed results to a separate channel and keeps going.
+	DeadLetter
+)
+
+// MapErr applies a function that may fail to values received from an input
+// channel and sends a Result for each of them to the output channel.
+// The function stops when the input channel is closed or the context is canceled.
+func MapErr[I, O any](ctx context.Context, id int, in <-chan I, fn func(context.Context, int, I) (O, error)) <-chan Result[O] {
+	out := make(chan Result[O])
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				o, err := fn(ctx, id, v)
+				select {
+				case out <- Result[O]{Out: o, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// FanOutErr distributes values from the input channel to multiple worker goroutines.
+// Each worker processes values using `fn` and sends a Result for each of them
+// to its own output channel.
+func FanOutErr[T, O any](ctx context.Context, in <-chan T, workerCount int, fn func(context.Context, T) (O, error)) []<-chan Result[O] {
+	return FanOut(ctx, in, workerCount, func(ctx context.Context, v T) Result[O] {
+		o, err := fn(ctx, v)
+		return Result[O]{Out: o, Err: err}
+	})
+}
+
+// FilterErr applies a set of functions that may fail to values received from
+// an input channel. If all functions return true the value is sent to the
+// output channel, if any function fails the value is sent along with the error.
+func FilterErr[T any](ctx context.Context, in <-chan T, fn ...func(T) (bool, error)) <-chan Result[T] {
+	out := make(chan Result[T], cap(in))
+	go func() {
+		defer close(out)
+	outer:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				r := Result[T]{Out: v}
+				for _, fn := range fn {
+					keep, err := fn(v)
+					if err != nil {
+						r.Err = err
+						break
+					}
+					if !keep {
+						continue outer
+					}
+				}
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ReduceErr applies a reducing function that may fail to all values from the
+// input channel and produces a single Result. The first failure stops the
+// reduction and is sent along with the accumulated value so far.
+func ReduceErr[L, R any](ctx context.Context, in <-chan R, fn func(L, R) (L, error), initial, zero L) <-chan Result[L] {
+	out := make(chan Result[L])
+	go func() {
+		defer close(out)
+		acc := initial
+		any := false
+	outer:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					break outer
+				}
+				next, err := fn(acc, v)
+				if err != nil {
+					select {
+					case out <- Result[L]{Out: acc, Err: err}:
+					case <-ctx.Done():
+					}
+					return
+				}
+				acc = next
+				any = true
+			}
+		}
+		r := Result[L]{Out: zero}
+		if any {
+			r.Out = acc
+		}
+		select {
+		case out <- r:
+		case <-ctx.Done():
+		}
+	}()
+	return out
+}
+
+// Errors splits a channel of results into successful values and failures
+// according to the given policy.
+//
+// With StopOnError the first failure is sent to the (buffered) failure channel,
+// `cancel` is called with the error as the cause, errgroup-style, and both
+// channels are closed; the cause can be read back with context.Cause.
+// `cancel` must cancel the context of the upstream stages so they stop too,
+// and Errors panics if it is nil.
+// With DeadLetter every failure is sent to the failure channel and the rest of
+// the stream keeps flowing; as with Partition both channels must be drained.
+func Errors[T any](ctx context.Context, in <-chan Result[T], policy ErrorPolicy, cancel context.CancelCauseFunc) (<-chan T, <-chan Result[T]) {
+	if policy == StopOnError && cancel == nil {
+		panic("pipeline: StopOnError requires a cancel function")
+	}
+	values := make(chan T)
+	failed := make(chan Result[T], 1)
+	go func() {
+		defer close(values)
+		defer close(failed)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case r, ok := <-in:
+				if !ok {
+					return
+				}
+				if r.Err == nil {
+					select {
+					case values <- r.Out:
+					case <-ctx.Done():
+						return
+					}
+					continue
+				}
+				if policy == StopOnError {
+					failed <- r
+					cancel(r.Err)
+					return
+				}
+				select {
+				case failed <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return values, failed
+}
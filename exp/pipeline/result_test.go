@@ -0,0 +1,154 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+var errOdd = errors.New("odd")
+
+func half(ctx context.Context, id int, val int) (int, error) {
+	if val%2 != 0 {
+		return 0, errOdd
+	}
+	return val / 2, nil
+}
+
+func TestMapErr(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4)
+
+	output := MapErr(ctx, 0, input, half)
+	var result []int
+	var failures int
+	for r := range output {
+		if r.Err != nil {
+			failures++
+			continue
+		}
+		result = append(result, r.Out)
+	}
+
+	AssertResults(t, result, []int{1, 2})
+	if failures != 2 {
+		t.Errorf("expected 2 failures, got %d", failures)
+	}
+}
+
+func TestFilterErr(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5, 6)
+
+	output := FilterErr(ctx, input, func(val int) (bool, error) {
+		if val == 5 {
+			return false, errOdd
+		}
+		return val%2 == 0, nil
+	})
+	var result []int
+	for r := range output {
+		if r.Err != nil {
+			if r.Out != 5 {
+				t.Errorf("expected failure for 5, got %d", r.Out)
+			}
+			continue
+		}
+		result = append(result, r.Out)
+	}
+
+	AssertResults(t, result, []int{2, 4, 6})
+}
+
+func TestReduceErr(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5)
+
+	output := ReduceErr(ctx, input, func(acc int, val int) (int, error) {
+		if val == 4 {
+			return acc, errOdd
+		}
+		return acc + val, nil
+	}, 0, -1)
+	r := <-output
+
+	if !errors.Is(r.Err, errOdd) || r.Out != 6 {
+		t.Errorf("expected (6, %v), got (%d, %v)", errOdd, r.Out, r.Err)
+	}
+}
+
+func TestErrorsStopOnError(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctx, stop := context.WithCancelCause(parent)
+	defer stop(nil)
+
+	input := Generator(ctx, 2, 4, 5, 6, 8)
+
+	values, failed := Errors(ctx, MapErr(ctx, 0, input, half), StopOnError, stop)
+	var result []int
+	for val := range values {
+		result = append(result, val)
+	}
+
+	AssertResults(t, result, []int{1, 2})
+	if r := <-failed; !errors.Is(r.Err, errOdd) {
+		t.Errorf("expected failure %v, got %v", errOdd, r.Err)
+	}
+	if !errors.Is(context.Cause(ctx), errOdd) {
+		t.Errorf("expected cause %v, got %v", errOdd, context.Cause(ctx))
+	}
+}
+
+func TestErrorsStopOnErrorNilCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected StopOnError without a cancel function to panic")
+		}
+	}()
+	Errors(ctx, make(chan Result[int]), StopOnError, nil)
+}
+
+func TestErrorsDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, 1, 2, 3, 4, 5, 6)
+
+	outputs := FanOutErr(ctx, input, 3, func(ctx context.Context, val int) (int, error) {
+		return half(ctx, 0, val)
+	})
+	values, failed := Errors(ctx, FanIn(ctx, outputs...), DeadLetter, nil)
+
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range failed {
+			n++
+		}
+		done <- n
+	}()
+	var result []int
+	for val := range values {
+		result = append(result, val)
+	}
+	slices.Sort(result)
+
+	AssertResults(t, result, []int{1, 2, 3})
+	if n := <-done; n != 3 {
+		t.Errorf("expected 3 dead letters, got %d", n)
+	}
+}
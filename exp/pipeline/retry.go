@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Backoff describes how Retry waits between attempts. The first wait is
+// `Initial`, each one after is `Multiplier` times longer up to `Max`, and
+// `Jitter` randomises each wait by up to that fraction of it either way.
+type Backoff struct {
+	Attempts   int
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+	// Retryable reports whether an error is worth retrying; nil retries all.
+	Retryable func(error) bool
+}
+
+// Retry wraps a stage function, such as one passed to MapErr or FanOutErr,
+// so that failed calls are retried according to `b`. It returns the last
+// error once the attempts are used up, the error is not retryable or the
+// context is canceled.
+func Retry[I, O any](b Backoff, fn func(context.Context, I) (O, error)) func(context.Context, I) (O, error) {
+	if b.Attempts < 1 {
+		b.Attempts = 1
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 2
+	}
+	return func(ctx context.Context, v I) (O, error) {
+		wait := b.Initial
+		for attempt := 1; ; attempt++ {
+			o, err := fn(ctx, v)
+			if err == nil || attempt >= b.Attempts || (b.Retryable != nil && !b.Retryable(err)) {
+				return o, err
+			}
+			d := wait
+			if b.Jitter > 0 {
+				d += time.Duration((rand.Float64()*2 - 1) * b.Jitter * float64(wait))
+			}
+			timer := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return o, err
+			case <-timer.C:
+			}
+			wait = time.Duration(float64(wait) * b.Multiplier)
+			if b.Max > 0 && wait > b.Max {
+				wait = b.Max
+			}
+		}
+	}
+}
+
+// ErrCircuitOpen is returned in place of calling a stage function while its
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// Closed lets every call through.
+	Closed BreakerState = iota
+	// Open short-circuits every call with ErrCircuitOpen.
+	Open
+	// HalfOpen lets a single probe call through to decide whether to close.
+	HalfOpen
+)
+
+// String returns the string representation of the state.
+func (s BreakerState) String() string {
+	var names = []string{
+		"Closed",
+		"Open",
+		"HalfOpen",
+	}
+	if Closed <= s && s <= HalfOpen {
+		return names[s]
+	}
+	return fmt.Sprintf("%%!BreakerState(%d)", s)
+}
+
+// CircuitBreaker opens after `threshold` consecutive failures, then once
+// `cooldown` has passed lets a single probe through; the circuit closes again
+// if the probe succeeds and reopens if it fails.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	opened    time.Time
+}
+
+// NewCircuitBreaker returns a closed circuit breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && time.Since(b.opened) >= b.cooldown {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a call may go ahead, claiming the probe if the
+// cooldown has passed.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Closed:
+		return true
+	case Open:
+		if time.Since(b.opened) >= b.cooldown {
+			b.state = HalfOpen
+			return true
+		}
+	}
+	return false
+}
+
+// record the outcome of a call.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.state = Closed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.state = Open
+		b.opened = time.Now()
+	}
+}
+
+// Breaker wraps a stage function, such as one passed to MapErr or FanOutErr,
+// so that calls are guarded by the circuit breaker `b`.
+func Breaker[I, O any](b *CircuitBreaker, fn func(context.Context, I) (O, error)) func(context.Context, I) (O, error) {
+	return func(ctx context.Context, v I) (O, error) {
+		if !b.allow() {
+			var zero O
+			return zero, ErrCircuitOpen
+		}
+		o, err := fn(ctx, v)
+		b.record(err)
+		return o, err
+	}
+}
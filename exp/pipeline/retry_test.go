@@ -0,0 +1,100 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+var errFlaky = errors.New("flaky")
+
+func TestRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	calls := 0
+	fn := Retry(Backoff{Attempts: 4, Initial: 10 * time.Millisecond, Multiplier: 2, Jitter: 0.1}, func(ctx context.Context, val int) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errFlaky
+		}
+		return val * 2, nil
+	})
+	result, err := fn(ctx, 21)
+
+	AssertExecutionTime(t, start, 30*time.Millisecond, 10*time.Millisecond)
+	if err != nil || result != 42 || calls != 3 {
+		t.Errorf("expected 42 after 3 calls, got %d, %v after %d calls", result, err, calls)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	errFatal := errors.New("fatal")
+	tests := []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{"attempts", errFlaky, 3},
+		{"retryable", errFatal, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			fn := Retry(Backoff{Attempts: 3, Initial: time.Millisecond, Retryable: func(err error) bool {
+				return errors.Is(err, errFlaky)
+			}}, func(ctx context.Context, val int) (int, error) {
+				calls++
+				return 0, tt.err
+			})
+			if _, err := fn(ctx, 1); !errors.Is(err, tt.err) || calls != tt.calls {
+				t.Errorf("expected %v after %d calls, got %v after %d calls", tt.err, tt.calls, err, calls)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	healthy := false
+	calls := 0
+	b := NewCircuitBreaker(2, 50*time.Millisecond)
+	fn := Breaker(b, func(ctx context.Context, val int) (int, error) {
+		calls++
+		if !healthy {
+			return 0, errFlaky
+		}
+		return val, nil
+	})
+
+	fn(ctx, 1)
+	fn(ctx, 2)
+	if _, err := fn(ctx, 3); !errors.Is(err, ErrCircuitOpen) || b.State() != Open || calls != 2 {
+		t.Fatalf("expected the circuit to open after 2 calls, got %v, %v after %d calls", err, b.State(), calls)
+	}
+
+	// a failed probe reopens the circuit
+	time.Sleep(60 * time.Millisecond)
+	if _, err := fn(ctx, 4); !errors.Is(err, errFlaky) || b.State() != Open {
+		t.Fatalf("expected the probe to fail and reopen, got %v, %v", err, b.State())
+	}
+
+	// a successful probe closes it
+	healthy = true
+	time.Sleep(60 * time.Millisecond)
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open after the cooldown, got %v", b.State())
+	}
+	if v, err := fn(ctx, 5); err != nil || v != 5 || b.State() != Closed {
+		t.Fatalf("expected the probe to succeed and close, got %d, %v, %v", v, err, b.State())
+	}
+}
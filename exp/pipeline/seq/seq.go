@@ -0,0 +1,126 @@
+// Package seq provides synchronous, iterator based equivalents of the
+// pipeline stages that don't need their own goroutine.
+package seq
+
+import (
+	"iter"
+	"slices"
+)
+
+// Take yields only the first n values of seq.
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			i++
+			if i >= n {
+				return
+			}
+		}
+	}
+}
+
+// Drop discards the first n values of seq and yields the rest.
+func Drop[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i := 0
+		for v := range seq {
+			if i < n {
+				i++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Filter yields the values of seq for which all functions return true.
+func Filter[T any](seq iter.Seq[T], fn ...func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+	outer:
+		for v := range seq {
+			for _, fn := range fn {
+				if !fn(v) {
+					continue outer
+				}
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk groups the values of seq into slices of a given size.
+// The final chunk may be smaller than the chunk size if there are not enough remaining values.
+func Chunk[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, size)
+		for v := range seq {
+			chunk = append(chunk, v)
+			if len(chunk) == size {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]T, 0, size)
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Window groups the values of seq into overlapping windows of a specified
+// size, starting a new window every `slide` values. The final window may be
+// smaller than the window size if it holds values not yet yielded.
+func Window[T any](seq iter.Seq[T], size int, slide int) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		window := make([]T, 0, size)
+		fresh, skip := 0, 0
+		for v := range seq {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			window = append(window, v)
+			fresh++
+			if len(window) == size {
+				if !yield(slices.Clone(window)) {
+					return
+				}
+				window = append(window[:0], window[min(slide, size):]...)
+				fresh, skip = 0, max(slide-size, 0)
+			}
+		}
+		if fresh > 0 {
+			yield(window)
+		}
+	}
+}
+
+// Distinct removes consecutive duplicate values from seq based on a key function.
+func Distinct[T any, K comparable](seq iter.Seq[T], keyFn func(T) K) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var last K
+		var first = true
+		for v := range seq {
+			this := keyFn(v)
+			if first || this != last {
+				last = this
+				first = false
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
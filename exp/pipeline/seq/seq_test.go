@@ -0,0 +1,36 @@
+package seq_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	. "github.com/cmilhench/x/exp/pipeline/seq"
+)
+
+func TestSeq(t *testing.T) {
+	input := slices.Values([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	isOdd := func(val int) bool { return val%2 != 0 }
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Take", slices.Collect(Take(input, 5)), []int{1, 2, 3, 4, 5}},
+		{"Drop", slices.Collect(Drop(input, 4)), []int{5, 6, 7, 8, 9, 10}},
+		{"Filter", slices.Collect(Filter(input, isOdd)), []int{1, 3, 5, 7, 9}},
+		{"Chunk", slices.Collect(Chunk(input, 3)), [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}, {10}}},
+		{"Window", slices.Collect(Window(input, 3, 2)), [][]int{{1, 2, 3}, {3, 4, 5}, {5, 6, 7}, {7, 8, 9}, {9, 10}}},
+		{"WindowGap", slices.Collect(Window(input, 2, 3)), [][]int{{1, 2}, {4, 5}, {7, 8}, {10}}},
+		{"Distinct", slices.Collect(Distinct(slices.Values([]int{1, 1, 2, 3, 3, 3, 1}), func(val int) int { return val })), []int{1, 2, 3, 1}},
+		{"Composed", slices.Collect(Take(Filter(Drop(input, 2), isOdd), 2)), []int{3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("%s() = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
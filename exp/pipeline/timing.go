@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+)
+
+// Edge selects which values ThrottleEdge sends from each window.
+type Edge int
+
+const (
+	// Leading sends the first value of a window as soon as it arrives.
+	Leading Edge = 1 << iota
+	// Trailing sends the last value of a window when the window ends.
+	Trailing
+)
+
+// Debounce sends a value only once `quiet` has passed without another value
+// arriving, discarding the values it replaces. A pending value is sent when
+// the input channel is closed.
+func Debounce[T any](ctx context.Context, in <-chan T, quiet time.Duration, opts ...Option) <-chan T {
+	o := newOptions(opts)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		timer := o.clock.NewTimer(quiet)
+		timer.Stop()
+		defer timer.Stop()
+		var latest T
+		pending := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						select {
+						case out <- latest:
+						case <-ctx.Done():
+						}
+					}
+					return
+				}
+				latest, pending = v, true
+				timer.Reset(quiet)
+			case <-timer.C():
+				if !pending {
+					continue
+				}
+				pending = false
+				select {
+				case out <- latest:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ThrottleEdge sends at most one value per `window`. A window starts when a
+// value arrives while none is open; with Leading that value is sent at once,
+// and with Trailing the last value received during the window is sent when
+// it ends, starting another window. Other values are discarded.
+// An `edge` of zero is treated as Leading.
+func ThrottleEdge[T any](ctx context.Context, in <-chan T, window time.Duration, edge Edge, opts ...Option) <-chan T {
+	if edge == 0 {
+		edge = Leading
+	}
+	o := newOptions(opts)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		timer := o.clock.NewTimer(window)
+		timer.Stop()
+		defer timer.Stop()
+		var latest T
+		open, pending := false, false
+		send := func(v T) bool {
+			select {
+			case out <- v:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					if pending && edge&Trailing != 0 {
+						send(latest)
+					}
+					return
+				}
+				if open {
+					latest, pending = v, true
+					continue
+				}
+				open = true
+				timer.Reset(window)
+				if edge&Leading != 0 {
+					if !send(v) {
+						return
+					}
+				} else {
+					latest, pending = v, true
+				}
+			case <-timer.C():
+				if pending && edge&Trailing != 0 {
+					pending = false
+					timer.Reset(window)
+					if !send(latest) {
+						return
+					}
+					continue
+				}
+				open, pending = false, false
+			}
+		}
+	}()
+	return out
+}
+
+// Audit sends the last value received during each `window`, a window
+// starting when a value arrives while none is open. Unlike ThrottleEdge with
+// Trailing, no window is started by sending. A pending value is sent when the
+// input channel is closed.
+func Audit[T any](ctx context.Context, in <-chan T, window time.Duration, opts ...Option) <-chan T {
+	o := newOptions(opts)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		timer := o.clock.NewTimer(window)
+		timer.Stop()
+		defer timer.Stop()
+		var latest T
+		pending := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						select {
+						case out <- latest:
+						case <-ctx.Done():
+						}
+					}
+					return
+				}
+				if !pending {
+					timer.Reset(window)
+				}
+				latest, pending = v, true
+			case <-timer.C():
+				pending = false
+				select {
+				case out <- latest:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,112 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmilhench/x/exp/clock"
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+// Collect reads the remaining values until the channel is closed.
+func Collect(t *testing.T, out <-chan int) []int {
+	t.Helper()
+	var result []int
+	for val := range out {
+		result = append(result, val)
+	}
+	return result
+}
+
+func TestDebounce(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	input := make(chan int)
+	output := Debounce(ctx, input, 100*time.Millisecond, WithClock(clk))
+
+	input <- 1
+	clk.BlockUntil(1)
+	clk.Advance(100 * time.Millisecond)
+	result := []int{<-output}
+
+	// 2 and 3 are each replaced before the quiet period passes
+	input <- 2
+	clk.BlockUntil(1)
+	clk.Advance(50 * time.Millisecond)
+	input <- 3
+	input <- 4
+	clk.Advance(50 * time.Millisecond)
+	close(input)
+	result = append(result, Collect(t, output)...)
+
+	AssertResults(t, result, []int{1, 4})
+}
+
+func TestThrottleEdge(t *testing.T) {
+	tests := []struct {
+		name string
+		edge Edge
+		want []int
+	}{
+		{"leading", Leading, []int{1}},
+		{"trailing", Trailing, []int{3}},
+		{"both", Leading | Trailing, []int{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			clk := clock.NewFake(time.Unix(0, 0))
+			input := make(chan int)
+			output := ThrottleEdge(ctx, input, 100*time.Millisecond, tt.edge, WithClock(clk))
+
+			var result []int
+			input <- 1
+			if tt.edge&Leading != 0 {
+				result = append(result, <-output)
+			}
+			input <- 2
+			input <- 3
+			clk.BlockUntil(1)
+			clk.Advance(100 * time.Millisecond)
+			if tt.edge&Trailing != 0 {
+				result = append(result, <-output)
+				// the trailing value starts another window, which ends empty
+				clk.BlockUntil(1)
+				clk.Advance(100 * time.Millisecond)
+			}
+			close(input)
+			result = append(result, Collect(t, output)...)
+
+			AssertResults(t, result, tt.want)
+		})
+	}
+}
+
+func TestAudit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	input := make(chan int)
+	output := Audit(ctx, input, 100*time.Millisecond, WithClock(clk))
+
+	input <- 1
+	input <- 2
+	clk.BlockUntil(1)
+	clk.Advance(100 * time.Millisecond)
+	result := []int{<-output}
+
+	input <- 3
+	clk.BlockUntil(1)
+	clk.Advance(100 * time.Millisecond)
+	result = append(result, <-output)
+	close(input)
+	result = append(result, Collect(t, output)...)
+
+	AssertResults(t, result, []int{2, 3})
+}
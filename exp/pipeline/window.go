@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// TimeWindow holds the values whose timestamps fall within [Start, End).
+type TimeWindow[T any] struct {
+	Start  time.Time
+	End    time.Time
+	Values []T
+}
+
+// TumblingWindow groups values into consecutive, non-overlapping windows of
+// duration `size` based on the timestamp returned by `ts`.
+// See SlidingWindow for how windows are aligned and closed, and late values
+// handled.
+func TumblingWindow[T any](ctx context.Context, in <-chan T, ts func(T) time.Time, size, lateness time.Duration) <-chan TimeWindow[T] {
+	return SlidingWindow(ctx, in, ts, size, size, lateness)
+}
+
+// SlidingWindow groups values into windows of duration `size` starting every
+// `slide`, based on the timestamp returned by `ts`; a value belongs to every
+// window that covers it. Windows start at multiples of `slide` since the Unix
+// epoch. It panics if `size` or `slide` is not positive.
+// Time is driven by the values themselves: a window is sent once a value has
+// been seen with a timestamp at least `lateness` beyond the window's end, and
+// values arriving after all their windows have been sent are dropped.
+// Windows still open when the input channel is closed are sent in order.
+func SlidingWindow[T any](ctx context.Context, in <-chan T, ts func(T) time.Time, size, slide, lateness time.Duration) <-chan TimeWindow[T] {
+	if size <= 0 || slide <= 0 {
+		panic("pipeline: non-positive size or slide for SlidingWindow")
+	}
+	out := make(chan TimeWindow[T])
+	go func() {
+		defer close(out)
+		var open []*TimeWindow[T]
+		var watermark time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					emitWindows(ctx, out, open, time.Time{})
+					return
+				}
+				t := ts(v)
+				for start := align(t, slide); start.Add(size).After(t); start = start.Add(-slide) {
+					end := start.Add(size)
+					if !watermark.IsZero() && !end.After(watermark) {
+						break // this window and all earlier ones are closed
+					}
+					i, found := slices.BinarySearchFunc(open, start, func(w *TimeWindow[T], s time.Time) int {
+						return w.Start.Compare(s)
+					})
+					if !found {
+						open = slices.Insert(open, i, &TimeWindow[T]{Start: start, End: end})
+					}
+					open[i].Values = append(open[i].Values, v)
+				}
+				if wm := t.Add(-lateness); wm.After(watermark) {
+					watermark = wm
+				}
+				if open, ok = emitWindows(ctx, out, open, watermark); !ok {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// SessionWindow groups values into sessions based on the timestamp returned by
+// `ts`, a session being closed after a `gap` with no values. Each session runs
+// from its first value until `gap` after its last. It panics if `gap` is not
+// positive.
+// See SlidingWindow for how windows are closed and late values handled.
+func SessionWindow[T any](ctx context.Context, in <-chan T, ts func(T) time.Time, gap, lateness time.Duration) <-chan TimeWindow[T] {
+	if gap <= 0 {
+		panic("pipeline: non-positive gap for SessionWindow")
+	}
+	out := make(chan TimeWindow[T])
+	go func() {
+		defer close(out)
+		var open []*TimeWindow[T]
+		var watermark time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					emitWindows(ctx, out, open, time.Time{})
+					return
+				}
+				t := ts(v)
+				session := &TimeWindow[T]{Start: t, End: t.Add(gap)}
+				// merge every open session that overlaps the new one
+				merged := open[:0]
+				joined := false
+				for _, w := range open {
+					if w.Start.Before(session.End) && session.Start.Before(w.End) {
+						if w.Start.Before(session.Start) {
+							session.Start = w.Start
+						}
+						if w.End.After(session.End) {
+							session.End = w.End
+						}
+						session.Values = append(session.Values, w.Values...)
+						joined = true
+						continue
+					}
+					merged = append(merged, w)
+				}
+				open = merged
+				if !joined && !watermark.IsZero() && !session.End.After(watermark) {
+					continue // late
+				}
+				session.Values = append(session.Values, v)
+				i, _ := slices.BinarySearchFunc(open, session.Start, func(w *TimeWindow[T], s time.Time) int {
+					return w.Start.Compare(s)
+				})
+				open = slices.Insert(open, i, session)
+				if wm := t.Add(-lateness); wm.After(watermark) {
+					watermark = wm
+				}
+				if open, ok = emitWindows(ctx, out, open, watermark); !ok {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// align returns the latest multiple of `d` since the Unix epoch at or before
+// `t`, unlike t.Truncate which counts from the zero time.
+func align(t time.Time, d time.Duration) time.Time {
+	offset := time.Duration(t.UnixNano() % int64(d))
+	if offset < 0 {
+		offset += d
+	}
+	return t.Round(0).Add(-offset)
+}
+
+// emitWindows sends, in start order, every window ending at or before the
+// watermark (all of them if it is zero) and returns those still open.
+func emitWindows[T any](ctx context.Context, out chan<- TimeWindow[T], open []*TimeWindow[T], watermark time.Time) ([]*TimeWindow[T], bool) {
+	remaining := open[:0]
+	for i, w := range open {
+		if !watermark.IsZero() && w.End.After(watermark) {
+			remaining = append(remaining, w)
+			continue
+		}
+		select {
+		case out <- *w:
+		case <-ctx.Done():
+			return append(remaining, open[i:]...), false
+		}
+	}
+	return remaining, true
+}
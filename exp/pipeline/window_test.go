@@ -0,0 +1,113 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/cmilhench/x/exp/pipeline"
+)
+
+type event struct {
+	at  int // seconds
+	val int
+}
+
+func at(e event) time.Time { return time.Unix(int64(e.at), 0) }
+
+func collect(t *testing.T, output <-chan TimeWindow[event]) (starts []int, values [][]int) {
+	t.Helper()
+	for w := range output {
+		starts = append(starts, int(w.Start.Unix()))
+		var vals []int
+		for _, e := range w.Values {
+			vals = append(vals, e.val)
+		}
+		values = append(values, vals)
+	}
+	return
+}
+
+func TestTumblingWindow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// 4 arrives late but within the allowed lateness, 5 does not
+	input := Generator(ctx, event{0, 1}, event{3, 2}, event{11, 3}, event{8, 4}, event{25, 6}, event{9, 5}, event{26, 7})
+
+	output := TumblingWindow(ctx, input, at, 10*time.Second, 5*time.Second)
+	starts, values := collect(t, output)
+
+	AssertResults(t, starts, []int{0, 10, 20})
+	AssertResults(t, values[0], []int{1, 2, 4})
+	AssertResults(t, values[1], []int{3})
+	AssertResults(t, values[2], []int{6, 7})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, event{1, 1}, event{6, 2}, event{11, 3}, event{16, 4})
+
+	output := SlidingWindow(ctx, input, at, 10*time.Second, 5*time.Second, 0)
+	starts, values := collect(t, output)
+
+	AssertResults(t, starts, []int{-5, 0, 5, 10, 15})
+	AssertResults(t, values[0], []int{1})
+	AssertResults(t, values[1], []int{1, 2})
+	AssertResults(t, values[2], []int{2, 3})
+	AssertResults(t, values[3], []int{3, 4})
+	AssertResults(t, values[4], []int{4})
+}
+
+func TestSessionWindow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// 4 bridges the gap between the first two sessions, 7 is too late
+	input := Generator(ctx, event{0, 1}, event{2, 2}, event{8, 3}, event{5, 4}, event{30, 5}, event{31, 6}, event{1, 7})
+
+	output := SessionWindow(ctx, input, at, 4*time.Second, 5*time.Second)
+	starts, values := collect(t, output)
+
+	AssertResults(t, starts, []int{0, 30})
+	AssertResults(t, values[0], []int{1, 2, 3, 4})
+	AssertResults(t, values[1], []int{5, 6})
+}
+
+func TestWindowAlignment(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	input := Generator(ctx, event{6, 1}, event{7, 2}, event{15, 3})
+
+	// windows start at multiples of 7s since the Unix epoch, not the zero time
+	output := TumblingWindow(ctx, input, at, 7*time.Second, 0)
+	starts, values := collect(t, output)
+
+	AssertResults(t, starts, []int{0, 7, 14})
+	AssertResults(t, values[0], []int{1})
+	AssertResults(t, values[1], []int{2})
+	AssertResults(t, values[2], []int{3})
+}
+
+func TestWindowInvalid(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for name, fn := range map[string]func(){
+		"size":  func() { SlidingWindow(ctx, Generator(ctx, event{}), at, 0, time.Second, 0) },
+		"slide": func() { SlidingWindow(ctx, Generator(ctx, event{}), at, time.Second, 0, 0) },
+		"gap":   func() { SessionWindow(ctx, Generator(ctx, event{}), at, -time.Second, 0) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a non-positive %s to panic", name)
+				}
+			}()
+			fn()
+		})
+	}
+}
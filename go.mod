@@ -1,5 +1,5 @@
 module github.com/cmilhench/x
 
-go 1.22.6
+go 1.24.0
 
 require github.com/gorilla/websocket v1.5.3